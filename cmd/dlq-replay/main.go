@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"log"
+	"payment-payments-api/internal/config"
+	"payment-payments-api/internal/kafka/consumer"
+	"time"
+)
+
+func init() {
+	flag.Int("max", 0, "maximum number of messages to replay, 0 replays all.")
+	flag.Duration("idle", 10*time.Second, "stop after no message arrives for this long.")
+
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	pflag.Parse()
+	viper.AutomaticEnv()
+	_ = viper.BindPFlags(pflag.CommandLine)
+}
+
+func main() {
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	replayer, err := consumer.NewDeadLetterReplayer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create Kafka dead letter replayer: %v", err)
+	}
+
+	replayed, err := replayer.Replay(viper.GetInt("max"), viper.GetDuration("idle"))
+	if err != nil {
+		log.Fatalf("Replayed %d messages from %s before failing: %v", replayed, cfg.DeadLetterTopic, err)
+	}
+
+	log.Printf("Replayed %d messages from %s to %s", replayed, cfg.DeadLetterTopic, cfg.ConsumerTopic)
+}
@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"log"
+	"net/http"
 	"payment-payments-api/internal/api"
 	"payment-payments-api/internal/config"
 	"payment-payments-api/internal/kafka/consumer"
 	"payment-payments-api/internal/kafka/producer"
+	"payment-payments-api/internal/kafka/relay"
 	"payment-payments-api/internal/repositories"
 	"payment-payments-api/internal/services"
-	"sync"
+	"payment-payments-api/pkg/auth"
+	"payment-payments-api/pkg/lifecycle"
+	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/vault"
 )
 
 func init() {
@@ -46,38 +54,157 @@ func main() {
 		panic(err)
 	}
 
-	paymentConsumer, err := consumer.NewPaymentConsumer(cfg)
+	deadLetterProducer, err := producer.NewDeadLetterProducer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create Kafka deadLetterProducer: %v", err)
+		panic(err)
+	}
+
+	paymentConsumer, err := consumer.NewPaymentConsumer(cfg, deadLetterProducer)
 	if err != nil {
 		log.Fatalf("Failed to create Kafka paymentConsumer: %v", err)
 		panic(err)
 	}
 
 	paymentRepository := repositories.NewPaymentRepository(db)
+	paymentEventRepository := repositories.NewPaymentEventRepository(db)
+	refundRepository := repositories.NewRefundRepository(db)
+	cardRepository := repositories.NewCardRepository(db)
+	ledgerRepository := repositories.NewLedgerRepository(db)
+	merchantRepository := repositories.NewMerchantRepository(db)
+	apiKeyRepository := repositories.NewApiKeyRepository(db)
+	userRepository := repositories.NewUserRepository(db)
+	tokenRepository := repositories.NewTokenRepository(db)
+
+	idempotencyRepository := repositories.NewIdempotencyRepository(db)
+	outboxRepository := repositories.NewOutboxRepository(db)
+	transactor := repositories.NewTransactor(db)
+
+	vaultCipher, err := vault.NewCipherFromBase64(cfg.VaultEncryptionKey)
+	if err != nil {
+		log.Fatalf("Invalid vaultEncryptionKey: %v", err)
+	}
+	bankCipher, err := vault.NewCipherFromBase64(cfg.BankEncryptionKey)
+	if err != nil {
+		log.Fatalf("Invalid bankEncryptionKey: %v", err)
+	}
+	fingerprintKey, err := base64.StdEncoding.DecodeString(cfg.VaultFingerprintKey)
+	if err != nil || len(fingerprintKey) == 0 {
+		log.Fatalf("Invalid vaultFingerprintKey: %v", err)
+	}
 
-	paymentService := services.NewPaymentService(paymentRepository, paymentProducer)
+	cursorKey, err := base64.StdEncoding.DecodeString(cfg.CursorSigningKey)
+	if err != nil || len(cursorKey) == 0 {
+		log.Fatalf("Invalid cursorSigningKey: %v", err)
+	}
 
-	services := &services.Services{
-		Payment: paymentService,
+	jwtKeys, err := auth.LoadKeySet(cfg.JwtKeysDir, cfg.JwtAlgorithm, cfg.JwtKeysRetained,
+		cfg.JwtKeyRotationInterval+cfg.AccessTokenTTL)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
 	}
 
-	server := api.NewServer(services)
+	mailer, err := config.InitMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create mailer: %v", err)
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+	cardService := services.NewCardService(cardRepository, vaultCipher, bankCipher, fingerprintKey)
+	ledgerService := services.NewLedgerService(ledgerRepository, cfg.PlatformFeeBps)
+	merchantService := services.NewMerchantService(merchantRepository)
+	apiKeyService := services.NewApiKeyService(apiKeyRepository, merchantService)
+	tokenService := services.NewTokenService(tokenRepository, userRepository, jwtKeys,
+		cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	userService := services.NewUserService(userRepository, merchantService, tokenService, vaultCipher, mailer, services.LoginPolicy{
+		MaxFailures:     cfg.LoginMaxFailures,
+		MaxIPFailures:   cfg.LoginMaxIPFailures,
+		LockoutDuration: cfg.LoginLockoutDuration,
+		FailureWindow:   cfg.LoginFailureWindow,
+		BaseDelay:       cfg.LoginBaseDelay,
+		MaxDelay:        cfg.LoginMaxDelay,
+	}, services.AccountEmails{
+		BaseURL:              cfg.AppBaseURL,
+		InviteTTL:            cfg.InviteTTL,
+		PasswordResetTTL:     cfg.PasswordResetTTL,
+		EmailVerificationTTL: cfg.EmailVerificationTTL,
+	})
+	if err = userService.BootstrapAdmin(cfg.AdminEmail, cfg.AdminPassword); err != nil {
+		log.Fatalf("Failed to create bootstrap admin: %v", err)
+	}
+	paymentService := services.NewPaymentService(paymentRepository, paymentEventRepository, refundRepository,
+		transactor, cardService, ledgerService, merchantService, cfg.AuthorizationWindow)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepository, cfg.IdempotencyRetention, cfg.IdempotencyLease)
 
-	go func() {
-		defer wg.Done()
+	services := &services.Services{
+		Payment:     paymentService,
+		User:        userService,
+		Idempotency: idempotencyService,
+		Card:        cardService,
+		Ledger:      ledgerService,
+		Merchant:    merchantService,
+		ApiKey:      apiKeyService,
+		Token:       tokenService,
+		Cursor:      umdw.NewCursorSigner(cursorKey),
+	}
 
-		err = server.Run(fmt.Sprintf(":%d", viper.GetInt("port")))
+	lc := lifecycle.New(cfg.ShutdownTimeout)
+
+	lc.Go("idempotency purge", func(ctx context.Context) error {
+		idempotencyService.PurgeExpiredEvery(ctx, cfg.IdempotencyPurgeInterval)
+		return nil
+	})
+	lc.Go("authorization expiry", func(ctx context.Context) error {
+		paymentService.ExpireAuthorizationsEvery(ctx, cfg.AuthorizationExpiryInterval)
+		return nil
+	})
+	lc.Go("token purge", func(ctx context.Context) error {
+		tokenService.PurgeExpiredEvery(ctx, cfg.TokenPurgeInterval)
+		return nil
+	})
+	lc.Go("key rotation", func(ctx context.Context) error {
+		tokenService.RotateKeysEvery(ctx, cfg.JwtKeyReloadInterval, cfg.JwtKeyRotationInterval)
+		return nil
+	})
+
+	outboxRelay := relay.NewOutboxRelay(cfg, outboxRepository, paymentProducer)
+	lc.Go("outbox relay", func(ctx context.Context) error {
+		outboxRelay.Relay(ctx)
+		return nil
+	})
+
+	lc.Go("payment consumer", func(ctx context.Context) error {
+		return paymentConsumer.Consume(ctx, services)
+	})
+
+	router, err := api.NewServer(services, cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid trustedProxies: %v", err)
+	}
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", viper.GetInt("port")),
+		Handler: router,
+	}
+	lc.Go("http server", func(ctx context.Context) error {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+	lc.OnStop("http server", server.Shutdown)
+
+	// Producers and the database are closed once the workers using them
+	// have returned.
+	lc.OnClose("payment producer", paymentProducer.Close)
+	lc.OnClose("dead-letter producer", deadLetterProducer.Close)
+	lc.OnClose("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
 		if err != nil {
-			panic(err)
+			return err
 		}
-	}()
-	go func() {
-		defer wg.Done()
+		return sqlDB.Close()
+	})
 
-		paymentConsumer.Consume(services)
-	}()
-
-	wg.Wait()
+	if err = lc.Wait(); err != nil {
+		log.Fatalf("Shutdown: %v", err)
+	}
 }
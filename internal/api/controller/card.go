@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+)
+
+var Card httpCard
+
+type httpCard struct{}
+
+func (httpCard) Tokenize(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.CardRequest
+		_ = umdw.BodyParse(&req, c)
+
+		res, err := s.Card.TokenizeCard(req)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Card tokenized successfully.", dto.MapCardToCardResponse(&res))
+	}
+}
@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/uhttp"
+)
+
+var Ledger httpLedger
+
+type httpLedger struct{}
+
+func (httpLedger) Integrity(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := s.Ledger.CheckIntegrity()
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Ledger integrity checked successfully.", report)
+	}
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/util"
+)
+
+var Merchant httpMerchant
+
+type httpMerchant struct{}
+
+func (httpMerchant) Create(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.MerchantRequest
+		_ = umdw.BodyParse(&req, c)
+
+		merchant, err := s.Merchant.CreateMerchant(req)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Merchant created successfully.", dto.MapMerchantToMerchantResponse(&merchant))
+	}
+}
+
+func (httpMerchant) Get(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		merchant, err := s.Merchant.GetMerchant(id)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Merchant retrieved successfully.", dto.MapMerchantToMerchantResponse(&merchant))
+	}
+}
+
+func (httpMerchant) Update(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.MerchantRequest
+		_ = umdw.BodyParse(&req, c)
+
+		merchant, err := s.Merchant.UpdateMerchant(id, req)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Merchant updated successfully.", dto.MapMerchantToMerchantResponse(&merchant))
+	}
+}
+
+func (httpMerchant) List(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := umdw.ListContext(c)
+		if err != nil {
+			uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+			return
+		}
+		if list.Keyset {
+			uhttp.Error(c, &util.RequiredFieldError{Message: "cursor paging is not supported for merchants"})
+			return
+		}
+
+		merchants, total, err := s.Merchant.ListMerchants(*list)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.SuccessList(c, "Merchants retrieved successfully.", merchants, uhttp.ListMeta{
+			Total: total,
+			Skip:  list.Skip,
+			Limit: list.Limit,
+		})
+	}
+}
+
+func (httpMerchant) SetEnabled(s *services.Services, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		merchant, err := s.Merchant.SetMerchantEnabled(id, enabled)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Merchant updated successfully.", dto.MapMerchantToMerchantResponse(&merchant))
+	}
+}
+
+func (httpMerchant) Balance(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		balance, err := s.Ledger.GetMerchantBalance(c.Params.ByName("id"))
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Merchant balance retrieved successfully.", balance)
+	}
+}
+
+func (httpMerchant) CreateApiKey(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.ApiKeyRequest
+		_ = umdw.BodyParse(&req, c)
+
+		key, err := s.ApiKey.CreateApiKey(id, req)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "API key created successfully.", key)
+	}
+}
+
+func (httpMerchant) ListApiKeys(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		keys, err := s.ApiKey.ListApiKeys(id)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "API keys retrieved successfully.", keys)
+	}
+}
+
+func (httpMerchant) RevokeApiKey(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		keyID, err := uuid.Parse(c.Params.ByName("keyId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		key, err := s.ApiKey.RevokeApiKey(id, keyID)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "API key revoked successfully.", dto.MapApiKeyToApiKeyResponse(&key))
+	}
+}
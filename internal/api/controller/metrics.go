@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"expvar"
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/services"
+)
+
+var Metrics httpMetrics
+
+type httpMetrics struct{}
+
+// Vars serves every published expvar, such as the consumer worker counters,
+// in the standard /debug/vars JSON shape.
+func (httpMetrics) Vars(s *services.Services) gin.HandlerFunc {
+	handler := expvar.Handler()
+	return func(c *gin.Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"github.com/google/uuid"
 	"net/http"
 	"payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/api/middleware"
 	"payment-payments-api/internal/services"
 	"payment-payments-api/pkg/uhttp"
 	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/util"
 )
 
 var Payment httpPayment
@@ -40,7 +42,84 @@ func (httpPayment) Refund(s *services.Services) gin.HandlerFunc {
 			return
 		}
 
-		uhttp.Success(c, "Payment created successfully.", res)
+		uhttp.Success(c, "Refund requested successfully.", dto.MapRefundToRefundResponse(&res))
+	}
+}
+
+func (httpPayment) List(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := umdw.ListContext(c)
+		if err != nil {
+			uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+			return
+		}
+		var req dto.PaymentListRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+			return
+		}
+		principal := middleware.GetPrincipal(c)
+		if principal.IsMerchant() {
+			req.MerchantID = principal.MerchantID
+		}
+		if principal.IsCustomer() {
+			req.UserID = principal.UserID
+		}
+
+		if list.Keyset {
+			listByCursor(c, s, req, *list)
+			return
+		}
+
+		payments, total, err := s.Payment.ListPayments(req, *list)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.SuccessList(c, "Payments retrieved successfully.", payments, uhttp.ListMeta{
+			Total: total,
+			Skip:  list.Skip,
+			Limit: list.Limit,
+		})
+	}
+}
+
+func (httpPayment) Capture(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.CaptureRequest
+		_ = umdw.BodyParse(&req, c)
+
+		payment, err := s.Payment.CapturePayment(id, req)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Capture requested successfully.", dto.MapPaymenToPaymentResponse(&payment))
+	}
+}
+
+func (httpPayment) Void(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		payment, err := s.Payment.VoidPayment(id)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Void requested successfully.", dto.MapPaymenToPaymentResponse(&payment))
 	}
 }
 
@@ -54,7 +133,7 @@ func (httpPayment) Get(s *services.Services) gin.HandlerFunc {
 		}
 
 		payment, err := s.Payment.GetPaymentByID(id)
-		if err != nil {
+		if err != nil || !middleware.GetPrincipal(c).CanAccessPayment(payment.UserID, payment.MerchantID) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
 			return
 		}
@@ -62,3 +141,64 @@ func (httpPayment) Get(s *services.Services) gin.HandlerFunc {
 		uhttp.Success(c, "Payment created successfully.", payment)
 	}
 }
+
+func (httpPayment) Events(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idParam := c.Params.ByName("id")
+		id, err := uuid.Parse(idParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		if !canAccessPayment(c, s, id) {
+			return
+		}
+
+		events, err := s.Payment.GetPaymentEvents(id)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Payment events retrieved successfully.", events)
+	}
+}
+
+// canAccessPayment answers 404 and returns false when the payment does not
+// exist or belongs to a customer or merchant other than the caller.
+func canAccessPayment(c *gin.Context, s *services.Services, id uuid.UUID) bool {
+	payment, err := s.Payment.GetPaymentByID(id)
+	if err != nil || !middleware.GetPrincipal(c).CanAccessPayment(payment.UserID, payment.MerchantID) {
+		uhttp.Error(c, services.PaymentNotFound)
+		return false
+	}
+	return true
+}
+
+func listByCursor(c *gin.Context, s *services.Services, req dto.PaymentListRequest, list umdw.List) {
+	var cursor *umdw.Cursor
+	if list.Cursor != "" {
+		var err error
+		cursor, err = s.Cursor.Decode(list.Cursor)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+	}
+
+	payments, page, err := s.Payment.ListPaymentsByCursor(req, list, cursor)
+	if err != nil {
+		uhttp.Error(c, err)
+		return
+	}
+
+	meta := uhttp.CursorMeta{Limit: list.Limit}
+	if page.Next != nil {
+		meta.Next = s.Cursor.Encode(*page.Next)
+	}
+	if page.Prev != nil {
+		meta.Prev = s.Cursor.Encode(*page.Prev)
+	}
+	uhttp.SuccessList(c, "Payments retrieved successfully.", payments, meta)
+}
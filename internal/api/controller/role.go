@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/dto"
+	middleware "payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+)
+
+var Role httpRole
+
+type httpRole struct{}
+
+func (httpRole) ListPolicies(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policies, err := s.User.ListRolePolicies()
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Role policies retrieved successfully.", policies)
+	}
+}
+
+func (httpRole) SetPolicy(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.RolePolicyRequest
+		_ = umdw.BodyParse(&req, c)
+
+		policy, err := s.User.SetRolePolicy(c.Params.ByName("role"), req, middleware.GetPrincipal(c).UserID)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Role policy updated successfully.", policy)
+	}
+}
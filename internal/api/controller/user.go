@@ -2,10 +2,12 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"payment-payments-api/internal/api/dto"
 	middleware "payment-payments-api/internal/api/middleware"
-	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/services"
-	"payment-payments-api/pkg/auth"
 	"payment-payments-api/pkg/uhttp"
 	"payment-payments-api/pkg/umdw"
 	"payment-payments-api/pkg/util"
@@ -23,69 +25,361 @@ func (httpUser) Login(s *services.Services) gin.HandlerFunc {
 			Password string `json:"password"`
 		}
 
-		type AuthResp struct {
-			User  *models.User `json:"user"`
-			Token string       `json:"token"`
+		var req AuthBody
+		_ = umdw.BodyParse(&req, c)
+
+		user, err := s.User.Login(req.Email, req.Password, c.ClientIP())
+		if err != nil {
+			uhttp.Error(c, err)
+			return
 		}
 
-		var req AuthBody
+		if purpose := s.User.LoginChallenge(user); purpose != "" {
+			challenge, err := s.Token.IssueChallenge(user, purpose)
+			if err != nil {
+				uhttp.Error(c, err)
+				return
+			}
+			uhttp.Success(c, "Two-factor authentication required", challenge)
+			return
+		}
+
+		tokens, err := s.Token.IssueTokens(user)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "User logged successfully", tokens)
+	}
+}
+
+func (httpUser) LoginTwoFactor(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.LoginTwoFactorRequest
+		_ = umdw.BodyParse(&req, c)
+
+		challenge, err := s.Token.ParseChallenge(req.ChallengeToken, services.ChallengeTwoFactor)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+		user, err := s.User.VerifyTwoFactor(challenge.UserID, req.Code, c.ClientIP())
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+		if err = s.Token.RevokeChallenge(challenge); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		tokens, err := s.Token.IssueTokens(user)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "User logged successfully", tokens)
+	}
+}
+
+func (httpUser) Refresh(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.RefreshTokenRequest
+		_ = umdw.BodyParse(&req, c)
+
+		tokens, err := s.Token.Refresh(req.RefreshToken)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Token refreshed successfully", tokens)
+	}
+}
+
+func (httpUser) Logout(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.LogoutRequest
+		_ = umdw.BodyParse(&req, c)
+
+		principal := middleware.GetPrincipal(c)
+		userID, _ := uuid.Parse(principal.UserID)
+		err := s.Token.Logout(userID, principal.TokenID, principal.TokenExpiresAt, req.RefreshToken)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "User logged out successfully", nil)
+	}
+}
+
+func (httpUser) Create(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.CreateUserRequest
 		_ = umdw.BodyParse(&req, c)
 
-		if req.Email == "admin@example.com" && req.Password == "admin123" {
-			token, _ := middleware.NewJwtToken(models.User{})
-			uhttp.Success(c, "User logged successfully", AuthResp{
-				User:  nil,
-				Token: token,
-			})
+		user, err := s.User.CreateUser(req)
+		if err != nil {
+			uhttp.Error(c, err)
 			return
 		}
 
-		user, err := s.User.GetUserByEmail(req.Email)
+		uhttp.Success(c, "User created successfully.", dto.MapUserToUserResponse(user))
+	}
+}
+
+func (httpUser) Get(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
 		if err != nil {
-			uhttp.Error(c, &util.JWTError{Message: "User cannot be logged."})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 			return
 		}
 
-		if !auth.VerifyPassword(req.Password, user.PasswordSalt, user.Password) {
-			uhttp.Error(c, &util.JWTError{Message: "User cannot be logged."})
+		user, err := s.User.GetUserById(id)
+		if err != nil {
+			uhttp.Error(c, err)
 			return
 		}
 
-		if !user.Enabled {
-			uhttp.Error(c, &util.JWTError{Message: "User cannot be logged."})
+		uhttp.Success(c, "User retrieved successfully.", dto.MapUserToUserResponse(user))
+	}
+}
+
+func (httpUser) List(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := umdw.ListContext(c)
+		if err != nil {
+			uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+			return
+		}
+		if list.Keyset {
+			uhttp.Error(c, &util.RequiredFieldError{Message: "cursor paging is not supported for users"})
 			return
 		}
 
-		user.CleanSensitiveInfo()
-		token, _ := middleware.NewJwtToken(*user)
+		users, total, err := s.User.ListUsers(*list)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
 
-		uhttp.Success(c, "User logged successfully", AuthResp{
-			User:  user,
-			Token: token,
+		uhttp.SuccessList(c, "Users retrieved successfully.", users, uhttp.ListMeta{
+			Total: total,
+			Skip:  list.Skip,
+			Limit: list.Limit,
 		})
 	}
 }
 
-func (httpUser) Create(s *services.Services) gin.HandlerFunc {
+func (httpUser) Update(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.UpdateUserRequest
+		_ = umdw.BodyParse(&req, c)
+
+		user, err := s.User.UpdateUser(id, req)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "User updated successfully.", dto.MapUserToUserResponse(user))
+	}
+}
+
+func (httpUser) SetEnabled(s *services.Services, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		user, err := s.User.SetUserEnabled(id, enabled)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "User updated successfully.", dto.MapUserToUserResponse(user))
+	}
+}
+
+func (httpUser) Unlock(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		if err = s.User.UnlockUser(id, middleware.GetPrincipal(c).UserID); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "User unlocked successfully.", nil)
+	}
+}
+
+func (httpUser) ChangePassword(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.ChangePasswordRequest
+		_ = umdw.BodyParse(&req, c)
+
+		principal := middleware.GetPrincipal(c)
+		requireCurrent := principal.UserID == id.String() || !principal.Role.Can(enums.PermissionUsersManage)
+		if err = s.User.ChangePassword(id, req, requireCurrent); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Password changed successfully.", nil)
+	}
+}
+
+func (httpUser) EnrollTwoFactor(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+
+		enrollment, err := s.User.EnrollTwoFactor(id)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Two-factor enrollment started.", enrollment)
+	}
+}
+
+func (httpUser) ConfirmTwoFactor(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.TwoFactorCodeRequest
+		_ = umdw.BodyParse(&req, c)
+
+		codes, err := s.User.ConfirmTwoFactor(id, req.Code)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Two-factor authentication enabled.", codes)
+	}
+}
+
+func (httpUser) DisableTwoFactor(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		var req dto.TwoFactorCodeRequest
+		_ = umdw.BodyParse(&req, c)
+
+		principal := middleware.GetPrincipal(c)
+		force := principal.UserID != id.String() && principal.Role.Can(enums.PermissionUsersManage)
+		if err = s.User.DisableTwoFactor(id, req.Code, force, principal.UserID); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Two-factor authentication disabled.", nil)
+	}
+}
+
+func (httpUser) AcceptInvite(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.AcceptInviteRequest
+		_ = umdw.BodyParse(&req, c)
+
+		user, err := s.User.AcceptInvite(req.Token, req.Password)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Invite accepted successfully.", dto.MapUserToUserResponse(user))
+	}
+}
+
+func (httpUser) ForgotPassword(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.ForgotPasswordRequest
+		_ = umdw.BodyParse(&req, c)
+
+		if err := s.User.ForgotPassword(req.Email); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "If the email belongs to an account, a reset link was sent.", nil)
+	}
+}
+
+func (httpUser) ResetPassword(s *services.Services) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var req dto.ResetPasswordRequest
+		_ = umdw.BodyParse(&req, c)
+
+		if err := s.User.ResetPassword(req.Token, req.NewPassword); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		uhttp.Success(c, "Password reset successfully.", nil)
+	}
+}
 
-		var req models.User
+func (httpUser) VerifyEmail(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.VerifyEmailRequest
 		_ = umdw.BodyParse(&req, c)
 
-		ue, _ := s.User.GetUserByEmail(req.Email)
-		if ue != nil {
-			uhttp.Error(c, &util.JWTError{Message: "User email already exists."})
+		if err := s.User.VerifyEmail(req.Token); err != nil {
+			uhttp.Error(c, err)
 			return
 		}
 
-		user, err := s.User.CreateUser(req.FirstName, req.LastName, req.Email)
+		uhttp.Success(c, "Email verified successfully.", nil)
+	}
+}
+
+func (httpUser) ResendVerification(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
 		if err != nil {
-			uhttp.Error(c, &util.JWTError{Message: err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 			return
 		}
 
-		user.CleanSensitiveInfo()
+		if err = s.User.ResendVerification(id); err != nil {
+			uhttp.Error(c, err)
+			return
+		}
 
-		uhttp.Success(c, "User created successfully.", user)
+		uhttp.Success(c, "Verification email sent.", nil)
 	}
 }
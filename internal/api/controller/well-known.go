@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-payments-api/internal/services"
+)
+
+var WellKnown httpWellKnown
+
+type httpWellKnown struct{}
+
+// JWKS serves the token verification keys as a bare JWK set, the shape
+// JWT libraries expect, rather than the usual response envelope.
+func (httpWellKnown) JWKS(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, s.Token.JWKS())
+	}
+}
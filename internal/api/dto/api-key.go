@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models"
+	"time"
+)
+
+func MapApiKeyToApiKeyResponse(model *models.ApiKey) ApiKeyResponse {
+	return ApiKeyResponse{
+		ApiKeyID:   model.ID,
+		MerchantID: model.MerchantID,
+		Name:       model.Name,
+		Prefix:     model.Prefix,
+		LastUsedAt: model.LastUsedAt,
+		RevokedAt:  model.RevokedAt,
+		CreatedAt:  model.CreatedAt,
+	}
+}
+
+type ApiKeyResponse struct {
+	ApiKeyID   uuid.UUID  `json:"apiKeyId"`
+	MerchantID uuid.UUID  `json:"merchantId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// ApiKeyCreatedResponse is returned once, when the key is created. The
+// plain key cannot be recovered afterwards.
+type ApiKeyCreatedResponse struct {
+	ApiKeyResponse
+	Key string `json:"key"`
+}
+
+type ApiKeyRequest struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"payment-payments-api/internal/models"
+	"time"
+)
+
+func MapCardToCardResponse(model *models.Card) CardResponse {
+	return CardResponse{
+		Token:     model.Token,
+		Bin:       model.Bin,
+		Last4:     model.Last4,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+type CardResponse struct {
+	Token     string    `json:"token"`
+	Bin       string    `json:"bin"`
+	Last4     string    `json:"last4"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CardRequest struct {
+	Number      string `json:"number"`
+	ExpiredDate string `json:"expiredDate"`
+	UserID      string `json:"userId"`
+}
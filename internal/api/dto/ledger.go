@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/pkg/money"
+)
+
+type MerchantBalanceResponse struct {
+	MerchantID string        `json:"merchantId"`
+	Balances   []money.Money `json:"balances"`
+}
+
+type LedgerIntegrityResponse struct {
+	Balanced   bool                        `json:"balanced"`
+	Journals   int64                       `json:"journals"`
+	Unbalanced []UnbalancedJournalResponse `json:"unbalanced"`
+}
+
+type UnbalancedJournalResponse struct {
+	JournalEntryID uuid.UUID   `json:"journalEntryId"`
+	Difference     money.Money `json:"difference"`
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+func MapMerchantToMerchantResponse(model *models.Merchant) MerchantResponse {
+	return MerchantResponse{
+		MerchantID:    model.ID,
+		Name:          model.Name,
+		Enabled:       model.Enabled,
+		Currencies:    model.Currencies,
+		PaymentLimits: model.PaymentLimits,
+		CreatedAt:     model.CreatedAt,
+		UpdatedAt:     model.UpdatedAt,
+	}
+}
+
+type MerchantResponse struct {
+	MerchantID    uuid.UUID     `json:"merchantId"`
+	Name          string        `json:"name"`
+	Enabled       bool          `json:"enabled"`
+	Currencies    []string      `json:"currencies"`
+	PaymentLimits []money.Money `json:"paymentLimits"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+}
+
+type MerchantRequest struct {
+	Name          string                `json:"name"`
+	Currencies    []string              `json:"currencies"`
+	PaymentLimits []PaymentLimitRequest `json:"paymentLimits"`
+}
+
+// PaymentLimitRequest caps the amount of a single payment in Currency.
+type PaymentLimitRequest struct {
+	Amount   money.Decimal `json:"amount"`
+	Currency string        `json:"currency"`
+}
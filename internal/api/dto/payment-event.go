@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+func MapPaymentEventToPaymentEventResponse(model *models.PaymentEvent) PaymentEventResponse {
+	return PaymentEventResponse{
+		Source:         model.Source,
+		PreviousStatus: model.PreviousStatus,
+		NewStatus:      model.NewStatus,
+		Msg:            model.Msg,
+		CreatedAt:      model.CreatedAt,
+	}
+}
+
+type PaymentEventResponse struct {
+	Source         enums.PaymentEventSource `json:"source"`
+	PreviousStatus enums.PaymentStatus      `json:"previousStatus"`
+	NewStatus      enums.PaymentStatus      `json:"newStatus"`
+	Msg            string                   `json:"msg"`
+	CreatedAt      time.Time                `json:"createdAt"`
+}
@@ -1,51 +1,90 @@
 package dto
 
 import (
+	"encoding/json"
 	"github.com/google/uuid"
 	"payment-payments-api/internal/models"
 	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
 	"time"
 )
 
 func MapPaymenToPaymentResponse(model *models.Payment) PaymentResponse {
 	return PaymentResponse{
-		PaymentID:     model.ID,
-		TransactionID: model.TransactionID,
-		RefundID:      model.RefundID,
-		UserID:        model.UserID,
-		MerchantID:    model.MerchantID,
-		Msg:           model.Msg,
-		Amount:        model.Amount,
-		Currency:      model.Currency,
-		Status:        model.Status,
-		Merchant:      model.Merchant,
-		CreatedAt:     model.CreatedAt,
-		UpdatedAt:     model.UpdatedAt,
+		PaymentID:              model.ID,
+		TransactionID:          model.TransactionID,
+		CardBin:                model.CardBin,
+		CardLast4:              model.CardLast4,
+		UserID:                 model.UserID,
+		MerchantID:             model.MerchantID,
+		Msg:                    model.Msg,
+		Amount:                 model.Amount.Number(),
+		Currency:               model.Amount.Currency,
+		AmountMoney:            model.Amount,
+		RefundedAmount:         model.RefundedAmount,
+		AuthorizedAmount:       model.AuthorizedAmount,
+		AuthorizationExpiresAt: model.AuthorizationExpiresAt,
+		Status:                 model.Status,
+		Merchant:               model.Merchant,
+		CreatedAt:              model.CreatedAt,
+		UpdatedAt:              model.UpdatedAt,
 	}
 }
 
 type PaymentResponse struct {
-	PaymentID     uuid.UUID           `json:"PaymentId"`
-	TransactionID string              `json:"transactionId"`
-	RefundID      string              `json:"refundID"`
-	UserID        string              `json:"userId"`
-	MerchantID    string              `json:"merchantId"`
-	Msg           string              `json:"msg"`
-	Amount        float64             `json:"amount"`
-	Currency      string              `json:"currency"`
-	Status        enums.PaymentStatus `json:"status"`
-	Merchant      string              `json:"merchant"`
-	CreatedAt     time.Time           `json:"createdAt"`
-	UpdatedAt     time.Time           `json:"updatedAt"`
+	PaymentID     uuid.UUID `json:"PaymentId"`
+	TransactionID string    `json:"transactionId"`
+	CardBin       string    `json:"cardBin"`
+	CardLast4     string    `json:"cardLast4"`
+	UserID        string    `json:"userId"`
+	MerchantID    string    `json:"merchantId"`
+	Msg           string    `json:"msg"`
+	// Amount and Currency keep their original shape, a decimal number and
+	// an ISO 4217 code; AmountMoney is the same amount as a Money object.
+	Amount                 json.Number         `json:"amount"`
+	Currency               string              `json:"currency"`
+	AmountMoney            money.Money         `json:"amountMoney"`
+	RefundedAmount         money.Money         `json:"refundedAmount"`
+	AuthorizedAmount       money.Money         `json:"authorizedAmount"`
+	AuthorizationExpiresAt *time.Time          `json:"authorizationExpiresAt,omitempty"`
+	Status                 enums.PaymentStatus `json:"status"`
+	Merchant               string              `json:"merchant"`
+	Refunds                []RefundResponse    `json:"refunds"`
+	CreatedAt              time.Time           `json:"createdAt"`
+	UpdatedAt              time.Time           `json:"updatedAt"`
 }
 
 type PaymentRequest struct {
-	CardID      string  `json:"cardId"`
-	CVC         string  `json:"cvc"`
-	ExpiredDate string  `json:"expiredDate"`
-	Amount      float64 `json:"amount"`
-	Currency    string  `json:"currency"`
-	Merchant    string  `json:"merchant"`
-	UserID      string  `json:"userId"`
-	MerchantID  string  `json:"merchantId"`
+	CardToken string        `json:"cardToken"`
+	CVC       string        `json:"cvc"`
+	Amount    money.Decimal `json:"amount"`
+	Currency  string        `json:"currency"`
+	// Merchant is ignored; payments carry the name of the merchant
+	// registered under MerchantID.
+	Merchant   string `json:"merchant"`
+	UserID     string `json:"userId"`
+	MerchantID string `json:"merchantId"`
+	// Capture defaults to true. When false the payment is only authorized
+	// and must be captured or voided later.
+	Capture *bool `json:"capture"`
+}
+
+// CaptureRequest captures an authorized payment. Without an amount the whole
+// authorized amount is captured.
+type CaptureRequest struct {
+	Amount   money.Decimal `json:"amount"`
+	Currency string        `json:"currency"`
+}
+
+// PaymentListRequest holds the GET /v1/payments filters. Amounts are decimals
+// in Currency, which is required when either bound is set. Dates are RFC 3339.
+type PaymentListRequest struct {
+	UserID      string        `form:"userId"`
+	MerchantID  string        `form:"merchantId"`
+	Status      string        `form:"status"`
+	Currency    string        `form:"currency"`
+	MinAmount   money.Decimal `form:"minAmount"`
+	MaxAmount   money.Decimal `form:"maxAmount"`
+	CreatedFrom string        `form:"createdFrom"`
+	CreatedTo   string        `form:"createdTo"`
 }
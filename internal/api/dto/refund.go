@@ -1,7 +1,39 @@
 package dto
 
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+func MapRefundToRefundResponse(model *models.Refund) RefundResponse {
+	return RefundResponse{
+		RefundID:     model.ID,
+		PaymentID:    model.PaymentID,
+		BankRefundID: model.BankRefundID,
+		Amount:       model.Amount,
+		Status:       model.Status,
+		Msg:          model.Msg,
+		CreatedAt:    model.CreatedAt,
+		UpdatedAt:    model.UpdatedAt,
+	}
+}
+
+type RefundResponse struct {
+	RefundID     uuid.UUID          `json:"refundId"`
+	PaymentID    uuid.UUID          `json:"paymentId"`
+	BankRefundID string             `json:"bankRefundId"`
+	Amount       money.Money        `json:"amount"`
+	Status       enums.RefundStatus `json:"status"`
+	Msg          string             `json:"msg"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	UpdatedAt    time.Time          `json:"updatedAt"`
+}
+
 type RefundRequest struct {
-	TransactionID string  `json:"transactionId"`
-	Amount        float64 `json:"amount"`
-	Currency      string  `json:"currency"`
+	TransactionID string        `json:"transactionId"`
+	Amount        money.Decimal `json:"amount"`
+	Currency      string        `json:"currency"`
 }
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// TokenResponse is returned on login and refresh. Token is a short-lived
+// access JWT; RefreshToken can be exchanged once for a new pair.
+type TokenResponse struct {
+	User                  UserResponse `json:"user"`
+	Token                 string       `json:"token"`
+	ExpiresAt             time.Time    `json:"expiresAt"`
+	RefreshToken          string       `json:"refreshToken"`
+	RefreshTokenExpiresAt time.Time    `json:"refreshTokenExpiresAt"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// LogoutRequest optionally names the refresh token to revoke along with the
+// access token used for the call.
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
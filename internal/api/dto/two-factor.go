@@ -0,0 +1,39 @@
+package dto
+
+import "time"
+
+// TwoFactorEnrollmentResponse carries the secret to add to an authenticator
+// app, both raw and as an otpauth:// URI for QR codes.
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorCodeRequest holds a TOTP code or a recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse lists new recovery codes. They are only shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// LoginChallengeResponse replaces the tokens of a login when a second
+// factor is needed. With Purpose "2fa" the challenge token is exchanged at
+// /users/login/2fa together with a code; with "2fa-enroll" the user's role
+// requires 2FA and the token only allows enrolling.
+type LoginChallengeResponse struct {
+	ChallengeToken string    `json:"challengeToken"`
+	Purpose        string    `json:"purpose"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+type RolePolicyRequest struct {
+	RequireTwoFactor bool `json:"requireTwoFactor"`
+}
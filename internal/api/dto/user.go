@@ -0,0 +1,79 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+func MapUserToUserResponse(model *models.User) UserResponse {
+	return UserResponse{
+		UserID:          model.ID,
+		FirstName:       model.FirstName,
+		LastName:        model.LastName,
+		Email:           model.Email,
+		Enabled:         model.Enabled,
+		EmailVerifiedAt: model.EmailVerifiedAt,
+		Role:            model.Role,
+		MerchantID:      model.MerchantID,
+		CreatedAt:       model.CreatedAt,
+		UpdatedAt:       model.UpdatedAt,
+	}
+}
+
+type UserResponse struct {
+	UserID          uuid.UUID  `json:"userId"`
+	FirstName       string     `json:"firstName"`
+	LastName        string     `json:"lastName"`
+	Email           string     `json:"email"`
+	Enabled         bool       `json:"enabled"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	Role            enums.Role `json:"role"`
+	MerchantID      string     `json:"merchantId,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// CreateUserRequest creates a user. Password is optional; without one the
+// user is emailed an invite to choose it.
+type CreateUserRequest struct {
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Role       string `json:"role"`
+	MerchantID string `json:"merchantId"`
+}
+
+type UpdateUserRequest struct {
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	Role       string `json:"role"`
+	MerchantID string `json:"merchantId"`
+}
+
+// ChangePasswordRequest changes a password. CurrentPassword is only
+// optional when an admin changes another user's password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type AcceptInviteRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
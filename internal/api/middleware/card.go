@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/util"
+)
+
+var Card httpCardMdw
+
+type httpCardMdw struct{}
+
+func (httpCardMdw) CreateValidation(c *gin.Context) {
+	require := []string{
+		"number",
+		"expiredDate",
+		"userId",
+	}
+
+	verify := umdw.VerificationFunctions{
+		"number":      CardNumberValidation,
+		"expiredDate": CardExpiryValidation,
+	}
+
+	err := umdw.BodyVerifyFields(c, require, verify)
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
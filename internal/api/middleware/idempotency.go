@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"log"
+	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/util"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const idempotencyKeyMaxLength = 255
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response for requests repeating an
+// Idempotency-Key already seen within scope. Keys are kept per principal, so
+// a key reused by another caller never replays someone else's response.
+// Requests without the header are passed through untouched. It must run
+// after the route's access checks.
+func Idempotency(s *services.Services, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			uhttp.Error(c, &util.RequiredFieldError{Message: IdempotencyKeyHeader + " is too long"})
+			return
+		}
+
+		hash, err := requestHash(c)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+
+		record, replay, err := s.Idempotency.Begin(principalScope(c, scope), key, hash)
+		if err != nil {
+			uhttp.Error(c, err)
+			return
+		}
+		if replay {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.Response)
+			c.Abort()
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				_ = s.Idempotency.Release(record)
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+
+		c.Next()
+
+		err = s.Idempotency.Complete(record, recorder.Status(), recorder.body.Bytes())
+		if err != nil {
+			log.Printf("Error storing idempotent response for key %s: %v", key, err)
+		}
+	}
+}
+
+// principalScope narrows scope to the caller: the merchant for API keys,
+// which any key of the merchant may retry with, and the user otherwise.
+func principalScope(c *gin.Context, scope string) string {
+	principal := GetPrincipal(c)
+	if principal.ApiKeyID != "" {
+		return scope + ":merchant:" + principal.MerchantID
+	}
+	return scope + ":user:" + principal.UserID
+}
+
+// requestHash fingerprints the request path and parsed body. json.Marshal
+// sorts map keys, so equivalent bodies hash the same regardless of key order.
+func requestHash(c *gin.Context) (string, error) {
+	body, err := json.Marshal(c.Keys[umdw.BodyKey])
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
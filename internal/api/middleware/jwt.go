@@ -2,50 +2,93 @@ package middleware
 
 import (
 	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/services"
 	"payment-payments-api/pkg/auth"
 	"payment-payments-api/pkg/uhttp"
+	"time"
 )
 
-func JwtValidation(c *gin.Context) {
-	jwtToken := c.GetHeader(auth.JwtAuthorizationHeader)
+// JwtValidation accepts only requests carrying a valid, unrevoked user JWT
+// and stores the user's Principal in the context.
+func JwtValidation(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := jwtPrincipal(c, s)
+		if !ok {
+			uhttp.CustomError(c, http.StatusUnauthorized, auth.JwtMessageUnauthorized)
+			return
+		}
+		c.Set(PrincipalKey, principal)
 
-	valid, err := auth.IsJwtTokenValid(jwtToken)
-	if err != nil || !valid {
-		uhttp.CustomError(c, http.StatusUnauthorized, auth.JwtMessageUnauthorized)
+		c.Next()
 	}
-
-	c.Next()
 }
 
-func NewJwtToken(payload interface{}) (string, error) {
+// EnrollmentValidation accepts a user JWT or a login challenge for
+// enrollment, so users whose role requires 2FA can enroll before their first
+// full login.
+func EnrollmentValidation(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := jwtPrincipal(c, s)
+		if !ok {
+			challenge, err := s.Token.ParseChallenge(c.GetHeader(auth.JwtAuthorizationHeader), services.ChallengeEnrollment)
+			if err != nil {
+				uhttp.CustomError(c, http.StatusUnauthorized, auth.JwtMessageUnauthorized)
+				return
+			}
+			principal = Principal{UserID: challenge.UserID.String(), TokenID: challenge.TokenID}
+		}
+		c.Set(PrincipalKey, principal)
 
-	var jwtPayload map[string]interface{}
+		c.Next()
+	}
+}
 
-	inBytes, err := json.Marshal(payload)
+// jwtPrincipal validates the request's JWT against the denylist and the
+// user's revoked sessions. Tokens without a jti predate revocation and are
+// refused, as are challenge tokens.
+func jwtPrincipal(c *gin.Context, s *services.Services) (Principal, bool) {
+	claims, err := s.Token.ParseAccessToken(c.GetHeader(auth.JwtAuthorizationHeader))
 	if err != nil {
-		return "", err
+		return Principal{}, false
+	}
+	if _, ok := claims[auth.JwtClaimPurpose]; ok {
+		return Principal{}, false
+	}
+	jti, _ := claims[auth.JwtClaimTokenID].(string)
+	if jti == "" {
+		return Principal{}, false
+	}
+	revoked, err := s.Token.IsRevoked(jti)
+	if err != nil || revoked {
+		return Principal{}, false
 	}
-	err = json.Unmarshal(inBytes, &jwtPayload)
+	user, err := claimsUser(claims)
 	if err != nil {
-		return "", err
+		return Principal{}, false
+	}
+	exp, _ := claims["exp"].(float64)
+	revoked, err = s.Token.IsSessionRevoked(user.ID, time.Unix(int64(exp), 0))
+	if err != nil || revoked {
+		return Principal{}, false
 	}
 
-	return auth.NewJwtToken(jwtPayload, nil)
+	return Principal{
+		UserID:         user.ID.String(),
+		MerchantID:     user.MerchantID,
+		Role:           user.Role,
+		TokenID:        jti,
+		TokenExpiresAt: time.Unix(int64(exp), 0),
+	}, true
 }
 
-func GetJwtToken(c *gin.Context) (models.User, error) {
+func claimsUser(claims jwt.MapClaims) (models.User, error) {
 	var user models.User
-	jwtToken := c.GetHeader(auth.JwtAuthorizationHeader)
-
-	jwtPayload, err := auth.GetJwtTokenMapClaims(jwtToken)
-	if err != nil {
-		return user, err
-	}
 
-	jsonBody, err := json.Marshal(jwtPayload)
+	jsonBody, err := json.Marshal(claims)
 	if err != nil {
 		return user, err
 	}
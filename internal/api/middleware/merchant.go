@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/util"
+)
+
+var Merchant httpMerchantMdw
+
+type httpMerchantMdw struct{}
+
+func (httpMerchantMdw) CreateValidation(c *gin.Context) {
+	require := []string{
+		"name",
+		"currencies",
+	}
+
+	verify := umdw.VerificationFunctions{
+		"currencies": CurrencyListValidation,
+	}
+
+	err := umdw.BodyVerifyFields(c, require, verify)
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"payment-payments-api/internal/services"
 	"payment-payments-api/pkg/uhttp"
 	"payment-payments-api/pkg/umdw"
 	"payment-payments-api/pkg/util"
@@ -13,17 +15,17 @@ type httpPaymentMdw struct{}
 
 func (httpPaymentMdw) CreateValidation(c *gin.Context) {
 	require := []string{
-		"cardId",
+		"cardToken",
 		"cvc",
-		"expiredDate",
 		"amount",
 		"currency",
-		"merchant",
 		"userId",
 		"merchantId",
 	}
 
-	verify := umdw.VerificationFunctions{}
+	verify := umdw.VerificationFunctions{
+		"cvc": CVCValidation,
+	}
 
 	err := umdw.BodyVerifyFields(c, require, verify)
 	if err != nil {
@@ -33,3 +35,37 @@ func (httpPaymentMdw) CreateValidation(c *gin.Context) {
 
 	c.Next()
 }
+
+func (httpPaymentMdw) CaptureValidation(c *gin.Context) {
+	var require []string
+	if _, err := umdw.GetPathFromMap(c.Keys[umdw.BodyKey].(map[string]interface{}), "amount"); err == nil {
+		require = []string{"currency"}
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+// Access answers 404 when the payment named by the :id route parameter does
+// not exist or belongs to a customer or merchant other than the caller.
+func (httpPaymentMdw) Access(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Params.ByName("id"))
+		if err != nil {
+			uhttp.Error(c, services.PaymentNotFound)
+			return
+		}
+		payment, err := s.Payment.GetPaymentByID(id)
+		if err != nil || !GetPrincipal(c).CanAccessPayment(payment.UserID, payment.MerchantID) {
+			uhttp.Error(c, services.PaymentNotFound)
+			return
+		}
+
+		c.Next()
+	}
+}
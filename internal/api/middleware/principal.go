@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/auth"
+	"payment-payments-api/pkg/uhttp"
+	"payment-payments-api/pkg/umdw"
+	"time"
+)
+
+const PrincipalKey = "principal"
+
+const messageForbidden = "Forbidden"
+
+// Principal is the caller of a request: a user signed in with a JWT, or a
+// merchant integration using an API key, which acts as a merchant operator
+// of the key's merchant. TokenID and TokenExpiresAt describe the JWT.
+type Principal struct {
+	UserID         string
+	MerchantID     string
+	ApiKeyID       string
+	Role           enums.Role
+	TokenID        string
+	TokenExpiresAt time.Time
+}
+
+func (p Principal) IsMerchant() bool {
+	return p.Role == enums.RoleMerchantOperator
+}
+
+func (p Principal) IsCustomer() bool {
+	return p.Role == enums.RoleCustomer
+}
+
+// CanAccessMerchant reports whether the principal may act on merchantID.
+// Merchant operators are limited to their own merchant.
+func (p Principal) CanAccessMerchant(merchantID string) bool {
+	return !p.IsMerchant() || (p.MerchantID != "" && p.MerchantID == merchantID)
+}
+
+// CanAccessPayment reports whether the principal may see a payment made by
+// userID to merchantID: customers only their own, merchant operators only
+// their merchant's.
+func (p Principal) CanAccessPayment(userID, merchantID string) bool {
+	if p.IsCustomer() {
+		return p.UserID != "" && p.UserID == userID
+	}
+	return p.CanAccessMerchant(merchantID)
+}
+
+// Authenticate accepts either an X-API-Key header or a user JWT and stores
+// the resulting Principal in the context.
+func Authenticate(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader(auth.ApiKeyHeader); key != "" {
+			apiKey, err := s.ApiKey.Authenticate(key)
+			if err != nil {
+				uhttp.CustomError(c, http.StatusUnauthorized, auth.JwtMessageUnauthorized)
+				return
+			}
+			c.Set(PrincipalKey, Principal{
+				MerchantID: apiKey.MerchantID.String(),
+				ApiKeyID:   apiKey.ID.String(),
+				Role:       enums.RoleMerchantOperator,
+			})
+			c.Next()
+			return
+		}
+
+		principal, ok := jwtPrincipal(c, s)
+		if !ok {
+			uhttp.CustomError(c, http.StatusUnauthorized, auth.JwtMessageUnauthorized)
+			return
+		}
+		c.Set(PrincipalKey, principal)
+
+		c.Next()
+	}
+}
+
+func GetPrincipal(c *gin.Context) Principal {
+	principal, _ := c.Get(PrincipalKey)
+	p, _ := principal.(Principal)
+	return p
+}
+
+// RequirePermission rejects principals whose role lacks permission. It must
+// run after Authenticate.
+func RequirePermission(permission enums.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GetPrincipal(c).Role.Can(permission) {
+			uhttp.CustomError(c, http.StatusForbidden, messageForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ScopeBody pins the request body to the principal: merchant operators to
+// their merchantId and customers to their userId. Missing values are filled
+// in and values naming someone else are rejected.
+func ScopeBody(c *gin.Context) {
+	principal := GetPrincipal(c)
+
+	var key, value string
+	switch {
+	case principal.IsMerchant():
+		key, value = "merchantId", principal.MerchantID
+	case principal.IsCustomer():
+		key, value = "userId", principal.UserID
+	default:
+		c.Next()
+		return
+	}
+
+	body, _ := c.Keys[umdw.BodyKey].(map[string]interface{})
+	if body == nil {
+		body = map[string]interface{}{}
+		c.Keys[umdw.BodyKey] = body
+	}
+	current, _ := body[key].(string)
+	if current == "" {
+		body[key] = value
+	} else if current != value {
+		uhttp.CustomError(c, http.StatusForbidden, key+" does not match the caller")
+		return
+	}
+
+	c.Next()
+}
+
+// MerchantParam limits merchant operators to the merchant named by the :id
+// route parameter.
+func MerchantParam(c *gin.Context) {
+	if !GetPrincipal(c).CanAccessMerchant(c.Params.ByName("id")) {
+		uhttp.CustomError(c, http.StatusForbidden, messageForbidden)
+		return
+	}
+
+	c.Next()
+}
+
+// RequireUser rejects API key callers, for routes such as key management
+// that only a signed-in user may use.
+func RequireUser(c *gin.Context) {
+	if GetPrincipal(c).ApiKeyID != "" {
+		uhttp.CustomError(c, http.StatusForbidden, messageForbidden)
+		return
+	}
+
+	c.Next()
+}
+
+// SelfOrPermission lets through users acting on themselves, named by the :id
+// route parameter, and principals whose role has permission.
+func SelfOrPermission(permission enums.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		self := principal.UserID != "" && principal.UserID == c.Params.ByName("id")
+		if !self && !principal.Role.Can(permission) {
+			uhttp.CustomError(c, http.StatusForbidden, messageForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
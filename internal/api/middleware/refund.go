@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/services"
 	"payment-payments-api/pkg/uhttp"
 	"payment-payments-api/pkg/umdw"
 	"payment-payments-api/pkg/util"
@@ -28,3 +29,19 @@ func (httpRefundMdw) CreateValidation(c *gin.Context) {
 
 	c.Next()
 }
+
+// Access answers 404 when the payment named by the body's transactionId does
+// not exist or belongs to a customer or merchant other than the caller.
+func (httpRefundMdw) Access(s *services.Services) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, _ := c.Keys[umdw.BodyKey].(map[string]interface{})
+		transactionID, _ := body["transactionId"].(string)
+		payment, err := s.Payment.GetPaymentByTransactionID(transactionID)
+		if err != nil || !GetPrincipal(c).CanAccessPayment(payment.UserID, payment.MerchantID) {
+			uhttp.Error(c, services.PaymentNotFound)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -30,3 +30,142 @@ func (httpUserMdw) LoginValidation(c *gin.Context) {
 
 	c.Next()
 }
+
+func (httpUserMdw) CreateValidation(c *gin.Context) {
+	require := []string{
+		"firstName",
+		"lastName",
+		"email",
+	}
+
+	verify := umdw.VerificationFunctions{
+		"email": EmailValidation,
+	}
+
+	err := umdw.BodyVerifyFields(c, require, verify)
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) ChangePasswordValidation(c *gin.Context) {
+	require := []string{
+		"newPassword",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) RefreshValidation(c *gin.Context) {
+	require := []string{
+		"refreshToken",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) LoginTwoFactorValidation(c *gin.Context) {
+	require := []string{
+		"challengeToken",
+		"code",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) TwoFactorCodeValidation(c *gin.Context) {
+	require := []string{
+		"code",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) AcceptInviteValidation(c *gin.Context) {
+	require := []string{
+		"token",
+		"password",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) ForgotPasswordValidation(c *gin.Context) {
+	require := []string{
+		"email",
+	}
+
+	verify := umdw.VerificationFunctions{
+		"email": EmailValidation,
+	}
+
+	err := umdw.BodyVerifyFields(c, require, verify)
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) ResetPasswordValidation(c *gin.Context) {
+	require := []string{
+		"token",
+		"newPassword",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
+
+func (httpUserMdw) VerifyEmailValidation(c *gin.Context) {
+	require := []string{
+		"token",
+	}
+
+	err := umdw.BodyVerifyFields(c, require, umdw.VerificationFunctions{})
+	if err != nil {
+		uhttp.Error(c, &util.RequiredFieldError{Message: err.Error()})
+		return
+	}
+
+	c.Next()
+}
@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"payment-payments-api/pkg/money"
 	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/vault"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var PasswordValidation = umdw.VerificationKeyFunction{
@@ -32,6 +35,30 @@ var NumberPositiveValidation = umdw.VerificationKeyFunction{
 	ErrMsg: "Number invalid. Ref: N > 0",
 }
 
+var CardNumberValidation = umdw.VerificationKeyFunction{
+	Func: func(val interface{}) bool {
+		s, ok := val.(string)
+		return ok && vault.ValidPAN(vault.NormalizePAN(s))
+	},
+	ErrMsg: "Card number invalid. Ref: 4111 1111 1111 1111",
+}
+
+var CardExpiryValidation = umdw.VerificationKeyFunction{
+	Func: func(val interface{}) bool {
+		s, ok := val.(string)
+		return ok && vault.ValidExpiry(s, time.Now())
+	},
+	ErrMsg: "Card expiry invalid or expired. Ref: 09/29",
+}
+
+var CVCValidation = umdw.VerificationKeyFunction{
+	Func: func(val interface{}) bool {
+		s, ok := val.(string)
+		return ok && vault.ValidCVC(s)
+	},
+	ErrMsg: "CVC invalid. Ref: 123",
+}
+
 var ObjectIDValidation = umdw.VerificationKeyFunction{
 	Func: func(val interface{}) bool {
 		_, err := primitive.ObjectIDFromHex(val.(string))
@@ -110,3 +137,20 @@ var OptionValidation = func(options []string) umdw.VerificationKeyFunction {
 		ErrMsg: "Option invalid. Ref. " + strings.Join(options, ", "),
 	}
 }
+
+var CurrencyListValidation = umdw.VerificationKeyFunction{
+	Func: func(val interface{}) bool {
+		currencies, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, c := range currencies {
+			s, ok := c.(string)
+			if !ok || !money.IsSupported(s) {
+				return false
+			}
+		}
+		return true
+	},
+	ErrMsg: "Currencies invalid. Ref: [\"USD\", \"MXN\"]",
+}
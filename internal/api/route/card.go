@@ -0,0 +1,20 @@
+package api_route
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/controller"
+	"payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+)
+
+func cardApi(r *gin.RouterGroup, s *services.Services) {
+
+	r.POST("",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionCardsCreate),
+		middleware.ScopeBody,
+		middleware.Card.CreateValidation,
+		controller.Card.Tokenize(s),
+	)
+}
@@ -0,0 +1,18 @@
+package api_route
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/controller"
+	"payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+)
+
+func ledgerApi(r *gin.RouterGroup, s *services.Services) {
+
+	r.GET("/integrity",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionLedgerRead),
+		controller.Ledger.Integrity(s),
+	)
+}
@@ -0,0 +1,82 @@
+package api_route
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/controller"
+	"payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+)
+
+func merchantApi(r *gin.RouterGroup, s *services.Services) {
+
+	r.POST("",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMerchantsManage),
+		middleware.Merchant.CreateValidation,
+		controller.Merchant.Create(s),
+	)
+
+	r.GET("",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMerchantsList),
+		controller.Merchant.List(s),
+	)
+
+	r.GET("/:id",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMerchantsRead),
+		middleware.MerchantParam,
+		controller.Merchant.Get(s),
+	)
+
+	r.PUT("/:id",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMerchantsManage),
+		middleware.Merchant.CreateValidation,
+		controller.Merchant.Update(s),
+	)
+
+	r.POST("/:id/enable",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMerchantsManage),
+		controller.Merchant.SetEnabled(s, true),
+	)
+
+	r.POST("/:id/disable",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMerchantsManage),
+		controller.Merchant.SetEnabled(s, false),
+	)
+
+	r.POST("/:id/api-keys",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionApiKeysManage),
+		middleware.RequireUser,
+		middleware.MerchantParam,
+		controller.Merchant.CreateApiKey(s),
+	)
+
+	r.GET("/:id/api-keys",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionApiKeysManage),
+		middleware.RequireUser,
+		middleware.MerchantParam,
+		controller.Merchant.ListApiKeys(s),
+	)
+
+	r.DELETE("/:id/api-keys/:keyId",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionApiKeysManage),
+		middleware.RequireUser,
+		middleware.MerchantParam,
+		controller.Merchant.RevokeApiKey(s),
+	)
+
+	r.GET("/:id/balance",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionBalancesRead),
+		middleware.MerchantParam,
+		controller.Merchant.Balance(s),
+	)
+}
@@ -0,0 +1,18 @@
+package api_route
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/controller"
+	"payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+)
+
+func metricsApi(r *gin.RouterGroup, s *services.Services) {
+
+	r.GET("",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionMetricsRead),
+		controller.Metrics.Vars(s),
+	)
+}
@@ -4,25 +4,62 @@ import (
 	"github.com/gin-gonic/gin"
 	"payment-payments-api/internal/api/controller"
 	"payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/services"
 )
 
 func paymentApi(r *gin.RouterGroup, s *services.Services) {
 
 	r.POST("",
-		middleware.JwtValidation,
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionPaymentsCreate),
+		middleware.ScopeBody,
 		middleware.Payment.CreateValidation,
+		middleware.Idempotency(s, "payments.create"),
 		controller.Payment.Create(s),
 	)
 
+	r.GET("",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionPaymentsRead),
+		controller.Payment.List(s),
+	)
+
 	r.GET("/:id",
-		middleware.JwtValidation,
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionPaymentsRead),
 		controller.Payment.Get(s),
 	)
 
+	r.GET("/:id/events",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionPaymentsRead),
+		controller.Payment.Events(s),
+	)
+
+	r.POST("/:id/capture",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionPaymentsCapture),
+		middleware.Payment.CaptureValidation,
+		middleware.Payment.Access(s),
+		middleware.Idempotency(s, "payments.capture"),
+		controller.Payment.Capture(s),
+	)
+
+	r.POST("/:id/void",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionPaymentsCapture),
+		middleware.Payment.Access(s),
+		middleware.Idempotency(s, "payments.void"),
+		controller.Payment.Void(s),
+	)
+
 	r.POST("/refund",
-		middleware.JwtValidation,
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionRefundsCreate),
 		middleware.Refund.CreateValidation,
+		middleware.Refund.Access(s),
+		middleware.Idempotency(s, "payments.refund"),
 		controller.Payment.Refund(s),
 	)
 }
@@ -0,0 +1,24 @@
+package api_route
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/controller"
+	"payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+)
+
+func roleApi(r *gin.RouterGroup, s *services.Services) {
+
+	r.GET("",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionUsersManage),
+		controller.Role.ListPolicies(s),
+	)
+
+	r.PUT("/:role",
+		middleware.Authenticate(s),
+		middleware.RequirePermission(enums.PermissionUsersManage),
+		controller.Role.SetPolicy(s),
+	)
+}
@@ -7,5 +7,11 @@ import (
 
 func SetRoutes(r *gin.RouterGroup, s *services.Services) {
 	paymentApi(r.Group("/payments"), s)
+	cardApi(r.Group("/cards"), s)
 	userApi(r.Group("/users"), s)
+	roleApi(r.Group("/roles"), s)
+	merchantApi(r.Group("/merchants"), s)
+	ledgerApi(r.Group("/ledger"), s)
+	metricsApi(r.Group("/metrics"), s)
+	wellKnownApi(r.Group("/.well-known"), s)
 }
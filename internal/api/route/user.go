@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"payment-payments-api/internal/api/controller"
 	apimiddleware "payment-payments-api/internal/api/middleware"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/services"
 )
 
@@ -14,4 +15,113 @@ func userApi(r *gin.RouterGroup, s *services.Services) {
 		controller.User.Login(s),
 	)
 
+	r.POST("/login/2fa",
+		apimiddleware.User.LoginTwoFactorValidation,
+		controller.User.LoginTwoFactor(s),
+	)
+
+	r.POST("/invite/accept",
+		apimiddleware.User.AcceptInviteValidation,
+		controller.User.AcceptInvite(s),
+	)
+
+	r.POST("/password/forgot",
+		apimiddleware.User.ForgotPasswordValidation,
+		controller.User.ForgotPassword(s),
+	)
+
+	r.POST("/password/reset",
+		apimiddleware.User.ResetPasswordValidation,
+		controller.User.ResetPassword(s),
+	)
+
+	r.POST("/verify-email",
+		apimiddleware.User.VerifyEmailValidation,
+		controller.User.VerifyEmail(s),
+	)
+
+	r.POST("/refresh",
+		apimiddleware.User.RefreshValidation,
+		controller.User.Refresh(s),
+	)
+
+	r.POST("/logout",
+		apimiddleware.JwtValidation(s),
+		controller.User.Logout(s),
+	)
+
+	r.POST("",
+		apimiddleware.Authenticate(s),
+		apimiddleware.RequirePermission(enums.PermissionUsersManage),
+		apimiddleware.User.CreateValidation,
+		controller.User.Create(s),
+	)
+
+	r.GET("",
+		apimiddleware.Authenticate(s),
+		apimiddleware.RequirePermission(enums.PermissionUsersManage),
+		controller.User.List(s),
+	)
+
+	r.GET("/:id",
+		apimiddleware.Authenticate(s),
+		apimiddleware.SelfOrPermission(enums.PermissionUsersManage),
+		controller.User.Get(s),
+	)
+
+	r.PUT("/:id",
+		apimiddleware.Authenticate(s),
+		apimiddleware.RequirePermission(enums.PermissionUsersManage),
+		controller.User.Update(s),
+	)
+
+	r.POST("/:id/enable",
+		apimiddleware.Authenticate(s),
+		apimiddleware.RequirePermission(enums.PermissionUsersManage),
+		controller.User.SetEnabled(s, true),
+	)
+
+	r.POST("/:id/disable",
+		apimiddleware.Authenticate(s),
+		apimiddleware.RequirePermission(enums.PermissionUsersManage),
+		controller.User.SetEnabled(s, false),
+	)
+
+	r.POST("/:id/unlock",
+		apimiddleware.Authenticate(s),
+		apimiddleware.RequirePermission(enums.PermissionUsersManage),
+		controller.User.Unlock(s),
+	)
+
+	r.PUT("/:id/password",
+		apimiddleware.Authenticate(s),
+		apimiddleware.SelfOrPermission(enums.PermissionUsersManage),
+		apimiddleware.User.ChangePasswordValidation,
+		controller.User.ChangePassword(s),
+	)
+
+	r.POST("/:id/verify-email/resend",
+		apimiddleware.Authenticate(s),
+		apimiddleware.SelfOrPermission(enums.PermissionUsersManage),
+		controller.User.ResendVerification(s),
+	)
+
+	r.POST("/:id/2fa/enroll",
+		apimiddleware.EnrollmentValidation(s),
+		apimiddleware.SelfOrPermission(enums.PermissionUsersManage),
+		controller.User.EnrollTwoFactor(s),
+	)
+
+	r.POST("/:id/2fa/confirm",
+		apimiddleware.EnrollmentValidation(s),
+		apimiddleware.SelfOrPermission(enums.PermissionUsersManage),
+		apimiddleware.User.TwoFactorCodeValidation,
+		controller.User.ConfirmTwoFactor(s),
+	)
+
+	r.DELETE("/:id/2fa",
+		apimiddleware.Authenticate(s),
+		apimiddleware.SelfOrPermission(enums.PermissionUsersManage),
+		controller.User.DisableTwoFactor(s),
+	)
 }
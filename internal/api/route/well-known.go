@@ -0,0 +1,14 @@
+package api_route
+
+import (
+	"github.com/gin-gonic/gin"
+	"payment-payments-api/internal/api/controller"
+	"payment-payments-api/internal/services"
+)
+
+func wellKnownApi(r *gin.RouterGroup, s *services.Services) {
+
+	r.GET("/jwks.json",
+		controller.WellKnown.JWKS(s),
+	)
+}
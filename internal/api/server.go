@@ -10,14 +10,19 @@ import (
 	"time"
 )
 
-func NewServer(s *services.Services) *gin.Engine {
-	r := gin.Default()
+// NewServer builds the API router. Only the trustedProxies may set the client
+// IP through forwarding headers.
+func NewServer(s *services.Services, trustedProxies []string) (*gin.Engine, error) {
+	r, err := newEngine(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
 	version := r.Group("/v1")
 
 	version.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch, http.MethodOptions},
-		AllowHeaders:     []string{"Origin", "Content-Type", " Content-Length", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", " Content-Length", "Authorization", "Idempotency-Key", "X-API-Key"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 		AllowWildcard:    true,
@@ -35,5 +40,13 @@ func NewServer(s *services.Services) *gin.Engine {
 
 	api_route.SetRoutes(version, s)
 
-	return r
+	return r, nil
+}
+
+func newEngine(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// clientIP returns the IP the login handlers throttle on for a request from
+// remoteAddr carrying forwardedFor.
+func clientIP(t *testing.T, trustedProxies []string, remoteAddr, forwardedFor string) string {
+	gin.SetMode(gin.TestMode)
+	r, err := newEngine(trustedProxies)
+	assert.Nil(t, err)
+	r.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestForgedForwardedForDoesNotChangeClientIP(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("203.0.113.7", clientIP(t, nil, "203.0.113.7:4711", "198.51.100.1"))
+	assert.Equal("203.0.113.7", clientIP(t, []string{}, "203.0.113.7:4711", "198.51.100.2"))
+	assert.Equal("203.0.113.7", clientIP(t, []string{"10.0.0.0/8"}, "203.0.113.7:4711", "198.51.100.3"))
+}
+
+func TestTrustedProxyForwardsClientIP(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("198.51.100.1", clientIP(t, []string{"10.0.0.0/8"}, "10.1.2.3:4711", "198.51.100.1"))
+}
+
+func TestInvalidTrustedProxy(t *testing.T) {
+	_, err := newEngine([]string{"not-an-ip"})
+	assert.NotNil(t, err)
+}
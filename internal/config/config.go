@@ -2,6 +2,7 @@ package config
 
 import (
 	"github.com/spf13/viper"
+	"time"
 )
 
 type Config struct {
@@ -9,11 +10,79 @@ type Config struct {
 	GroupID          string
 	ConsumerTopic    string
 	ProducerTopic    string
+	DeadLetterTopic  string
 	DBUser           string
 	DBPassword       string
 	DBName           string
 	DBHost           string
 	DBPort           int64
+
+	IdempotencyRetention     time.Duration
+	IdempotencyLease         time.Duration
+	IdempotencyPurgeInterval time.Duration
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxLease        time.Duration
+	OutboxRetryBackoff time.Duration
+	OutboxMaxBackoff   time.Duration
+	OutboxMaxAttempts  int
+
+	ConsumerRetryAttempts  int
+	ConsumerRetryBackoff   time.Duration
+	ConsumerMaxBackoff     time.Duration
+	ConsumerWorkers        int
+	ConsumerQueueSize      int
+	ConsumerCommitInterval time.Duration
+	ConsumerDrainTimeout   time.Duration
+
+	VaultEncryptionKey  string
+	VaultFingerprintKey string
+	BankEncryptionKey   string
+
+	CursorSigningKey string
+
+	AuthorizationWindow         time.Duration
+	AuthorizationExpiryInterval time.Duration
+
+	PlatformFeeBps int64
+
+	AdminEmail    string
+	AdminPassword string
+
+	Mailer       string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	AppBaseURL           string
+	InviteTTL            time.Duration
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+
+	JwtKeysDir             string
+	JwtAlgorithm           string
+	JwtKeysRetained        int
+	JwtKeyRotationInterval time.Duration
+	JwtKeyReloadInterval   time.Duration
+
+	LoginMaxFailures     int
+	LoginMaxIPFailures   int
+	LoginLockoutDuration time.Duration
+	LoginFailureWindow   time.Duration
+	LoginBaseDelay       time.Duration
+	LoginMaxDelay        time.Duration
+
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	TokenPurgeInterval time.Duration
+
+	ShutdownTimeout time.Duration
+
+	TrustedProxies []string
 }
 
 func LoadConfig() (*Config, error) {
@@ -21,6 +90,7 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("groupId", "payment-payments-group")
 	viper.SetDefault("consumerTopic", "com.deuna.payment.payment.v1.payments.updated")
 	viper.SetDefault("producerTopic", "com.deuna.payment.payment_banking_x.v1.payments")
+	viper.SetDefault("deadLetterTopic", "com.deuna.payment.payment.v1.payments.updated.dlq")
 
 	viper.SetDefault("dbUser", "root")
 	viper.SetDefault("dbPassword", "root")
@@ -28,6 +98,61 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("dbHost", "localhost")
 	viper.SetDefault("dbPort", int64(5432))
 
+	viper.SetDefault("idempotencyRetention", 24*time.Hour)
+	viper.SetDefault("idempotencyLease", time.Minute)
+	viper.SetDefault("idempotencyPurgeInterval", time.Hour)
+
+	viper.SetDefault("outboxPollInterval", time.Second)
+	viper.SetDefault("outboxBatchSize", 100)
+	viper.SetDefault("outboxLease", 30*time.Second)
+	viper.SetDefault("outboxRetryBackoff", time.Second)
+	viper.SetDefault("outboxMaxBackoff", 5*time.Minute)
+	viper.SetDefault("outboxMaxAttempts", 20)
+
+	viper.SetDefault("consumerRetryAttempts", 3)
+	viper.SetDefault("consumerRetryBackoff", 500*time.Millisecond)
+	viper.SetDefault("consumerMaxBackoff", 10*time.Second)
+	viper.SetDefault("consumerWorkers", 8)
+	viper.SetDefault("consumerQueueSize", 100)
+	viper.SetDefault("consumerCommitInterval", time.Second)
+	viper.SetDefault("consumerDrainTimeout", 10*time.Second)
+
+	viper.SetDefault("authorizationWindow", 7*24*time.Hour)
+	viper.SetDefault("authorizationExpiryInterval", time.Minute)
+
+	viper.SetDefault("platformFeeBps", int64(0))
+
+	viper.SetDefault("jwtKeysDir", "keys")
+	viper.SetDefault("jwtAlgorithm", "ES256")
+	viper.SetDefault("jwtKeysRetained", 2)
+	viper.SetDefault("jwtKeyRotationInterval", 30*24*time.Hour)
+	viper.SetDefault("jwtKeyReloadInterval", time.Minute)
+
+	viper.SetDefault("mailer", "file")
+	viper.SetDefault("mailFrom", "no-reply@localhost")
+	viper.SetDefault("mailDir", "mail")
+	viper.SetDefault("smtpPort", 587)
+
+	viper.SetDefault("appBaseUrl", "http://localhost:5002")
+	viper.SetDefault("inviteTTL", 72*time.Hour)
+	viper.SetDefault("passwordResetTTL", time.Hour)
+	viper.SetDefault("emailVerificationTTL", 48*time.Hour)
+
+	viper.SetDefault("loginMaxFailures", 5)
+	viper.SetDefault("loginMaxIPFailures", 50)
+	viper.SetDefault("loginLockoutDuration", 15*time.Minute)
+	viper.SetDefault("loginFailureWindow", 15*time.Minute)
+	viper.SetDefault("loginBaseDelay", time.Second)
+	viper.SetDefault("loginMaxDelay", 30*time.Second)
+
+	viper.SetDefault("accessTokenTTL", 15*time.Minute)
+	viper.SetDefault("refreshTokenTTL", 30*24*time.Hour)
+	viper.SetDefault("tokenPurgeInterval", time.Hour)
+
+	viper.SetDefault("shutdownTimeout", 30*time.Second)
+
+	viper.SetDefault("trustedProxies", []string{})
+
 	viper.AutomaticEnv()
 
 	config := &Config{
@@ -35,12 +160,80 @@ func LoadConfig() (*Config, error) {
 		GroupID:          viper.GetString("groupId"),
 		ConsumerTopic:    viper.GetString("consumerTopic"),
 		ProducerTopic:    viper.GetString("producerTopic"),
+		DeadLetterTopic:  viper.GetString("deadLetterTopic"),
 
 		DBUser:     viper.GetString("dbUser"),
 		DBPassword: viper.GetString("dbPassword"),
 		DBName:     viper.GetString("dbName"),
 		DBHost:     viper.GetString("dbHost"),
 		DBPort:     viper.GetInt64("dbPort"),
+
+		IdempotencyRetention:     viper.GetDuration("idempotencyRetention"),
+		IdempotencyLease:         viper.GetDuration("idempotencyLease"),
+		IdempotencyPurgeInterval: viper.GetDuration("idempotencyPurgeInterval"),
+
+		OutboxPollInterval: viper.GetDuration("outboxPollInterval"),
+		OutboxBatchSize:    viper.GetInt("outboxBatchSize"),
+		OutboxLease:        viper.GetDuration("outboxLease"),
+		OutboxRetryBackoff: viper.GetDuration("outboxRetryBackoff"),
+		OutboxMaxBackoff:   viper.GetDuration("outboxMaxBackoff"),
+		OutboxMaxAttempts:  viper.GetInt("outboxMaxAttempts"),
+
+		ConsumerRetryAttempts:  viper.GetInt("consumerRetryAttempts"),
+		ConsumerRetryBackoff:   viper.GetDuration("consumerRetryBackoff"),
+		ConsumerMaxBackoff:     viper.GetDuration("consumerMaxBackoff"),
+		ConsumerWorkers:        viper.GetInt("consumerWorkers"),
+		ConsumerQueueSize:      viper.GetInt("consumerQueueSize"),
+		ConsumerCommitInterval: viper.GetDuration("consumerCommitInterval"),
+		ConsumerDrainTimeout:   viper.GetDuration("consumerDrainTimeout"),
+
+		VaultEncryptionKey:  viper.GetString("vaultEncryptionKey"),
+		VaultFingerprintKey: viper.GetString("vaultFingerprintKey"),
+		BankEncryptionKey:   viper.GetString("bankEncryptionKey"),
+
+		CursorSigningKey: viper.GetString("cursorSigningKey"),
+
+		AuthorizationWindow:         viper.GetDuration("authorizationWindow"),
+		AuthorizationExpiryInterval: viper.GetDuration("authorizationExpiryInterval"),
+
+		PlatformFeeBps: viper.GetInt64("platformFeeBps"),
+
+		AdminEmail:    viper.GetString("adminEmail"),
+		AdminPassword: viper.GetString("adminPassword"),
+
+		Mailer:       viper.GetString("mailer"),
+		MailFrom:     viper.GetString("mailFrom"),
+		MailDir:      viper.GetString("mailDir"),
+		SMTPHost:     viper.GetString("smtpHost"),
+		SMTPPort:     viper.GetInt("smtpPort"),
+		SMTPUsername: viper.GetString("smtpUsername"),
+		SMTPPassword: viper.GetString("smtpPassword"),
+
+		AppBaseURL:           viper.GetString("appBaseUrl"),
+		InviteTTL:            viper.GetDuration("inviteTTL"),
+		PasswordResetTTL:     viper.GetDuration("passwordResetTTL"),
+		EmailVerificationTTL: viper.GetDuration("emailVerificationTTL"),
+
+		JwtKeysDir:             viper.GetString("jwtKeysDir"),
+		JwtAlgorithm:           viper.GetString("jwtAlgorithm"),
+		JwtKeysRetained:        viper.GetInt("jwtKeysRetained"),
+		JwtKeyRotationInterval: viper.GetDuration("jwtKeyRotationInterval"),
+		JwtKeyReloadInterval:   viper.GetDuration("jwtKeyReloadInterval"),
+
+		LoginMaxFailures:     viper.GetInt("loginMaxFailures"),
+		LoginMaxIPFailures:   viper.GetInt("loginMaxIPFailures"),
+		LoginLockoutDuration: viper.GetDuration("loginLockoutDuration"),
+		LoginFailureWindow:   viper.GetDuration("loginFailureWindow"),
+		LoginBaseDelay:       viper.GetDuration("loginBaseDelay"),
+		LoginMaxDelay:        viper.GetDuration("loginMaxDelay"),
+
+		AccessTokenTTL:     viper.GetDuration("accessTokenTTL"),
+		RefreshTokenTTL:    viper.GetDuration("refreshTokenTTL"),
+		TokenPurgeInterval: viper.GetDuration("tokenPurgeInterval"),
+
+		ShutdownTimeout: viper.GetDuration("shutdownTimeout"),
+
+		TrustedProxies: viper.GetStringSlice("trustedProxies"),
 	}
 
 	return config, nil
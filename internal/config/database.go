@@ -22,10 +22,59 @@ func InitDB(cfg *Config) (*gorm.DB, error) {
 }
 
 func Migrate(DB *gorm.DB) error {
-	err := DB.AutoMigrate(&models.Payment{})
+	err := DB.AutoMigrate(
+		&models.Payment{},
+		&models.IdempotencyKey{},
+		&models.OutboxMessage{},
+		&models.RejectedTransition{},
+		&models.ProcessedEvent{},
+		&models.PaymentEvent{},
+		&models.Refund{},
+		&models.Card{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.Merchant{},
+		&models.ApiKey{},
+		&models.User{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.RevokedSession{},
+		&models.LoginThrottle{},
+		&models.AuditEntry{},
+		&models.RecoveryCode{},
+		&models.RolePolicy{},
+		&models.UserToken{},
+		&models.QuarantinedAmount{},
+	)
 	if err != nil {
 		log.Println("Error al migrar la base de datos:", err)
 		return err
 	}
+	err = migrateMoney(DB)
+	if err != nil {
+		log.Println("Error al migrar los montos:", err)
+		return err
+	}
+	err = migrateCardIDs(DB)
+	if err != nil {
+		log.Println("Error al migrar las tarjetas:", err)
+		return err
+	}
+	err = migrateCardOwners(DB)
+	if err != nil {
+		log.Println("Error al asignar las tarjetas:", err)
+		return err
+	}
+	err = migrateAuthorizedAmounts(DB)
+	if err != nil {
+		log.Println("Error al migrar los montos autorizados:", err)
+		return err
+	}
+	err = migratePendingCaptures(DB)
+	if err != nil {
+		log.Println("Error al migrar las capturas pendientes:", err)
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"fmt"
+	"payment-payments-api/pkg/mail"
+)
+
+// InitMailer returns the mailer named by cfg.Mailer: "smtp", "file", which
+// writes messages to cfg.MailDir, or "memory".
+func InitMailer(cfg *Config) (mail.Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "file":
+		return mail.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+	case "memory":
+		return mail.NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+	"sort"
+	"strings"
+)
+
+// migrateCardIDs replaces the card_id column, which held card numbers in
+// clear, with the BIN and last four digits and drops it.
+func migrateCardIDs(DB *gorm.DB) error {
+	migrator := DB.Migrator()
+	if !migrator.HasColumn("payments", "card_id") {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE payments SET
+			card_bin = CASE WHEN length(cards.digits) >= 12 THEN left(cards.digits, 6) ELSE '' END,
+			card_last4 = CASE WHEN length(cards.digits) >= 12 THEN right(cards.digits, 4) ELSE '' END
+			FROM (SELECT id, regexp_replace(coalesce(card_id, ''), '\D', '', 'g') AS digits FROM payments) cards
+			WHERE payments.id = cards.id`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn("payments", "card_id")
+	})
+}
+
+// migrateCardOwners gives cards tokenized without a user the user of the
+// payments made with them. Cards used by several users, or never used, stay
+// unowned and can no longer be paid with.
+func migrateCardOwners(DB *gorm.DB) error {
+	result := DB.Exec(`UPDATE cards SET user_id = owners.user_id
+		FROM (SELECT card_token, min(user_id) AS user_id FROM payments
+			WHERE card_token <> '' AND user_id <> ''
+			GROUP BY card_token HAVING count(DISTINCT user_id) = 1) owners
+		WHERE cards.token = owners.card_token AND coalesce(cards.user_id, '') = ''`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Assigned owners to %d cards", result.RowsAffected)
+	}
+
+	var unowned int64
+	if err := DB.Table("cards").Where("coalesce(user_id, '') = ''").Count(&unowned).Error; err != nil {
+		return err
+	}
+	if unowned > 0 {
+		log.Printf("%d cards have no owner and cannot be used", unowned)
+	}
+	return nil
+}
+
+// migrateAuthorizedAmounts fills the authorized amount of payments created
+// before authorization and capture were split, which were captured in full.
+func migrateAuthorizedAmounts(DB *gorm.DB) error {
+	return DB.Exec(`UPDATE payments SET authorized_amount_minor = amount_minor,
+		authorized_amount_currency = amount_currency
+		WHERE authorized_amount_minor IS NULL`).Error
+}
+
+// migratePendingCaptures moves the amount of captures awaiting the bank,
+// which used to replace the payment amount right away, to the capture amount
+// that only becomes the payment amount once the bank approves.
+func migratePendingCaptures(DB *gorm.DB) error {
+	return DB.Exec(`UPDATE payments SET capture_amount_minor = amount_minor,
+		capture_amount_currency = amount_currency,
+		amount_minor = authorized_amount_minor,
+		amount_currency = authorized_amount_currency
+		WHERE status = ? AND capture_amount_minor IS NULL`, enums.CapturePending).Error
+}
+
+type legacyAmountColumn struct {
+	column string
+	prefix string
+}
+
+// legacyDefaultExponent converts amounts whose currency is unknown.
+const legacyDefaultExponent = 2
+
+// migrateMoney converts the float64 amount columns written before amounts
+// were stored as money.Money into minor units. The legacy columns are only
+// dropped once every row was converted and checked; until then the
+// migration runs again on the next start. It is a no-op once they are gone.
+func migrateMoney(DB *gorm.DB) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := migrateLegacyAmounts(tx, "payments", "currency",
+			legacyAmountColumn{column: "amount", prefix: "amount_"},
+			legacyAmountColumn{column: "refunded_amount", prefix: "refunded_amount_"},
+		)
+		if err != nil {
+			return err
+		}
+		return migrateLegacyAmounts(tx, "refunds", "currency",
+			legacyAmountColumn{column: "amount", prefix: "amount_"},
+		)
+	})
+}
+
+// migrateLegacyAmounts converts the legacy columns of table in SQL. Rows
+// with an unknown currency or too many decimals are not rejected: they are
+// converted with the default exponent or rounded, and their original values
+// are kept in quarantined_amounts.
+func migrateLegacyAmounts(tx *gorm.DB, table, currencyColumn string, columns ...legacyAmountColumn) error {
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(table, currencyColumn) {
+		return nil
+	}
+
+	var legacy []legacyAmountColumn
+	for _, c := range columns {
+		if migrator.HasColumn(table, c.column) {
+			legacy = append(legacy, c)
+		}
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+	if err := createExponentsTable(tx); err != nil {
+		return err
+	}
+
+	currency := fmt.Sprintf("upper(trim(coalesce(t.%s, '')))", currencyColumn)
+	exponent := fmt.Sprintf(
+		"coalesce((SELECT e.exponent FROM legacy_currency_exponents e WHERE e.currency = %s), %d)",
+		currency, legacyDefaultExponent)
+	known := fmt.Sprintf("EXISTS (SELECT 1 FROM legacy_currency_exponents e WHERE e.currency = %s)", currency)
+
+	verified := true
+	for _, c := range legacy {
+		amount := fmt.Sprintf("coalesce(t.%s, 0)::numeric", c.column)
+		scaled := fmt.Sprintf("(%s * power(10::numeric, %s))", amount, exponent)
+
+		quarantine := fmt.Sprintf(`INSERT INTO quarantined_amounts
+			(source_table, row_id, "column", amount, currency, reason, created_at)
+			SELECT ?, t.id::text, ?, %[1]s, coalesce(t.%[2]s, ''),
+				CASE WHEN NOT %[3]s THEN 'unsupported currency' ELSE 'too many decimals' END, now()
+			FROM %[4]s t
+			WHERE t.%[5]sminor IS NULL AND (NOT %[3]s OR %[6]s <> round(%[6]s))
+			ON CONFLICT DO NOTHING`, amount, currencyColumn, known, table, c.prefix, scaled)
+		result := tx.Exec(quarantine, table, c.column)
+		if result.Error != nil {
+			return fmt.Errorf("quarantining %s.%s: %w", table, c.column, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Quarantined %d legacy amounts of %s.%s", result.RowsAffected, table, c.column)
+		}
+
+		convert := fmt.Sprintf(`UPDATE %[1]s t SET %[2]sminor = round(%[3]s)::bigint, %[2]scurrency = %[4]s
+			WHERE t.%[2]sminor IS NULL`, table, c.prefix, scaled, currency)
+		result = tx.Exec(convert)
+		if result.Error != nil {
+			return fmt.Errorf("converting %s.%s: %w", table, c.column, result.Error)
+		}
+		log.Printf("Converted %d legacy amounts of %s.%s to minor units", result.RowsAffected, table, c.column)
+
+		// Every legacy row must now hold its amount exactly, unless it was
+		// quarantined. Rows written since have no legacy amount.
+		var mismatched int64
+		check := fmt.Sprintf(`SELECT count(*) FROM %[1]s t
+			WHERE t.%[2]sminor IS NULL OR (t.%[4]s IS NOT NULL AND t.%[2]sminor <> %[3]s AND NOT EXISTS (
+				SELECT 1 FROM quarantined_amounts q
+				WHERE q.source_table = ? AND q.row_id = t.id::text AND q."column" = ?))`,
+			table, c.prefix, scaled, c.column)
+		if err := tx.Raw(check, table, c.column).Scan(&mismatched).Error; err != nil {
+			return fmt.Errorf("verifying %s.%s: %w", table, c.column, err)
+		}
+		if mismatched > 0 {
+			log.Printf("Keeping legacy column %s.%s: %d rows did not convert cleanly", table, c.column, mismatched)
+			verified = false
+		}
+	}
+	if !verified {
+		// New rows carry no legacy amount, so the kept columns must accept
+		// NULL.
+		for _, c := range legacy {
+			err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, c.column)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, currencyColumn)).Error
+	}
+
+	for _, c := range legacy {
+		if err := migrator.DropColumn(table, c.column); err != nil {
+			return err
+		}
+	}
+	return migrator.DropColumn(table, currencyColumn)
+}
+
+// createExponentsTable loads the supported currencies and their exponents
+// into a temporary table dropped when the transaction ends.
+func createExponentsTable(tx *gorm.DB) error {
+	err := tx.Exec(`CREATE TEMP TABLE IF NOT EXISTS legacy_currency_exponents
+		(currency text PRIMARY KEY, exponent int NOT NULL) ON COMMIT DROP`).Error
+	if err != nil {
+		return err
+	}
+
+	exponents := money.Exponents()
+	currencies := make([]string, 0, len(exponents))
+	for currency := range exponents {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	rows := make([]string, 0, len(currencies))
+	args := make([]interface{}, 0, 2*len(currencies))
+	for _, currency := range currencies {
+		rows = append(rows, "(?, ?)")
+		args = append(args, currency, exponents[currency])
+	}
+	return tx.Exec("INSERT INTO legacy_currency_exponents (currency, exponent) VALUES "+
+		strings.Join(rows, ", ")+" ON CONFLICT DO NOTHING", args...).Error
+}
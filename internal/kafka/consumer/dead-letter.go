@@ -0,0 +1,119 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"log"
+	"payment-payments-api/internal/config"
+	"payment-payments-api/internal/kafka/producer"
+	"strconv"
+	"time"
+)
+
+type DeadLetterReplayer interface {
+	Replay(max int, idle time.Duration) (int, error)
+}
+
+func NewDeadLetterReplayer(cfg *config.Config) (*deadLetterReplayer, error) {
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.BootstrapServers,
+		"group.id":           cfg.GroupID + "-dlq-replay",
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cfg.BootstrapServers})
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &deadLetterReplayer{
+		consumer:        c,
+		producer:        p,
+		deadLetterTopic: cfg.DeadLetterTopic,
+		topic:           cfg.ConsumerTopic,
+	}, nil
+}
+
+type deadLetterReplayer struct {
+	consumer        *kafka.Consumer
+	producer        *kafka.Producer
+	deadLetterTopic string
+	topic           string
+}
+
+// Replay moves up to max dead-lettered messages (0 means all) back to the
+// payment update topic, stopping once no message arrives for idle. Each
+// message is committed on the dead-letter topic only after it was delivered.
+func (r *deadLetterReplayer) Replay(max int, idle time.Duration) (int, error) {
+	defer r.consumer.Close()
+	defer r.producer.Close()
+
+	err := r.consumer.Subscribe(r.deadLetterTopic, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error subscribing to topic: %w", err)
+	}
+
+	replayed := 0
+	for max == 0 || replayed < max {
+		msg, err := r.consumer.ReadMessage(idle)
+		if err != nil {
+			var kafkaErr kafka.Error
+			if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrTimedOut {
+				return replayed, nil
+			}
+			return replayed, err
+		}
+
+		if err = r.republish(msg); err != nil {
+			return replayed, err
+		}
+		if _, err = r.consumer.CommitMessage(msg); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func (r *deadLetterReplayer) republish(message *kafka.Message) error {
+	deliveryChan := make(chan kafka.Event)
+	defer close(deliveryChan)
+
+	err := r.producer.Produce(replayMessage(message, r.topic), deliveryChan)
+	if err != nil {
+		return fmt.Errorf("failed to replay message: %w", err)
+	}
+
+	e := <-deliveryChan
+	msg := e.(*kafka.Message)
+	if msg.TopicPartition.Error != nil {
+		return fmt.Errorf("replay delivery failed: %v", msg.TopicPartition.Error)
+	}
+
+	log.Printf("Replayed dead letter %v to %v", message.TopicPartition, msg.TopicPartition)
+	return nil
+}
+
+// replayMessage builds the message sending a dead letter back to topic: the
+// original key, payload and headers, with the failure headers replaced by an
+// incremented replay count.
+func replayMessage(message *kafka.Message, topic string) *kafka.Message {
+	headers := producer.StripDeadLetterHeaders(message.Headers)
+	headers = append(headers, kafka.Header{
+		Key:   producer.DeadLetterHeaderReplays,
+		Value: []byte(strconv.Itoa(producer.DeadLetterReplays(message.Headers) + 1)),
+	})
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            message.Key,
+		Value:          message.Value,
+		Headers:        headers,
+	}
+}
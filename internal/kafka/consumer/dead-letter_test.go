@@ -0,0 +1,53 @@
+package consumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"payment-payments-api/internal/kafka/producer"
+	"testing"
+)
+
+func TestReplayMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []kafka.Header
+		replays string
+	}{
+		{"first replay", []kafka.Header{
+			{Key: "trace-id", Value: []byte("abc")},
+			{Key: producer.DeadLetterHeaderError, Value: []byte("boom")},
+			{Key: producer.DeadLetterHeaderReplays, Value: []byte("0")},
+		}, "1"},
+		{"replayed before", []kafka.Header{
+			{Key: "trace-id", Value: []byte("abc")},
+			{Key: producer.DeadLetterHeaderOriginalOffset, Value: []byte("42")},
+			{Key: producer.DeadLetterHeaderReplays, Value: []byte("2")},
+		}, "3"},
+		{"without dead-letter headers", []kafka.Header{
+			{Key: "trace-id", Value: []byte("abc")},
+		}, "1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			deadLetters := "payments.updated.dlq"
+			letter := &kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &deadLetters, Partition: 3, Offset: 7},
+				Key:            []byte("p-1"),
+				Value:          []byte(`{"paymentID":"p-1"}`),
+				Headers:        test.headers,
+			}
+
+			msg := replayMessage(letter, "payments.updated")
+
+			assert.Equal("payments.updated", *msg.TopicPartition.Topic)
+			assert.Equal(kafka.PartitionAny, msg.TopicPartition.Partition)
+			assert.Equal(letter.Key, msg.Key)
+			assert.Equal(letter.Value, msg.Value)
+			assert.Equal([]kafka.Header{
+				{Key: "trace-id", Value: []byte("abc")},
+				{Key: producer.DeadLetterHeaderReplays, Value: []byte(test.replays)},
+			}, msg.Headers)
+		})
+	}
+}
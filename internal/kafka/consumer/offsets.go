@@ -0,0 +1,155 @@
+package consumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"sort"
+	"sync"
+)
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets tracks the messages of one partition handed to workers.
+// pending holds their offsets in read order; handled ones are dropped from
+// its front, moving next up to the offset following the last of them.
+type partitionOffsets struct {
+	pending   []kafka.Offset
+	done      map[kafka.Offset]bool
+	next      kafka.Offset
+	committed kafka.Offset
+}
+
+// offsetTracker works out which offsets can be committed while workers
+// finish messages out of order. A partition's offset only moves past a
+// message once it and every message read before it were handled.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: map[partitionKey]*partitionOffsets{}}
+}
+
+// Add records a message as being handled. Reading an offset at or before
+// one already seen means the partition was rewound, e.g. after a
+// rebalance, so its earlier state is dropped.
+func (t *offsetTracker) Add(tp kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := topicPartitionKey(tp)
+	p, ok := t.partitions[key]
+	if !ok || (len(p.pending) > 0 && tp.Offset <= p.pending[len(p.pending)-1]) || tp.Offset < p.next {
+		p = &partitionOffsets{done: map[kafka.Offset]bool{}, next: kafka.OffsetInvalid, committed: kafka.OffsetInvalid}
+		t.partitions[key] = p
+	}
+	p.pending = append(p.pending, tp.Offset)
+}
+
+// Done marks a message as handled.
+func (t *offsetTracker) Done(tp kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[topicPartitionKey(tp)]
+	if !ok || len(p.pending) == 0 || tp.Offset < p.pending[0] {
+		return
+	}
+	p.done[tp.Offset] = true
+	for len(p.pending) > 0 && p.done[p.pending[0]] {
+		delete(p.done, p.pending[0])
+		p.next = p.pending[0] + 1
+		p.pending = p.pending[1:]
+	}
+}
+
+// Tracks reports whether tp was added and is not handled yet. Messages of a
+// dropped partition are no longer tracked.
+func (t *offsetTracker) Tracks(tp kafka.TopicPartition) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[topicPartitionKey(tp)]
+	if !ok || p.done[tp.Offset] {
+		return false
+	}
+	i := sort.Search(len(p.pending), func(i int) bool { return p.pending[i] >= tp.Offset })
+	return i < len(p.pending) && p.pending[i] == tp.Offset
+}
+
+// Committable returns the offsets that moved since they were last
+// committed, in the form CommitOffsets expects: the next offset to read.
+func (t *offsetTracker) Committable() []kafka.TopicPartition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var offsets []kafka.TopicPartition
+	for key, p := range t.partitions {
+		if p.next == kafka.OffsetInvalid || p.next == p.committed {
+			continue
+		}
+		topic := key.topic
+		offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: key.partition, Offset: p.next})
+	}
+	return offsets
+}
+
+// Committed records offsets returned by Committable as committed.
+func (t *offsetTracker) Committed(offsets []kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tp := range offsets {
+		if p, ok := t.partitions[topicPartitionKey(tp)]; ok && tp.Offset > p.committed {
+			p.committed = tp.Offset
+		}
+	}
+}
+
+// InFlight returns how many messages were read but not handled yet.
+func (t *offsetTracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	inFlight := 0
+	for _, p := range t.partitions {
+		inFlight += len(p.pending) - len(p.done)
+	}
+	return inFlight
+}
+
+// InFlightOf returns how many messages of partitions were read but not
+// handled yet.
+func (t *offsetTracker) InFlightOf(partitions []kafka.TopicPartition) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	inFlight := 0
+	for _, tp := range partitions {
+		if p, ok := t.partitions[topicPartitionKey(tp)]; ok {
+			inFlight += len(p.pending) - len(p.done)
+		}
+	}
+	return inFlight
+}
+
+// Drop forgets partitions, as once they were revoked from this consumer.
+func (t *offsetTracker) Drop(partitions []kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tp := range partitions {
+		delete(t.partitions, topicPartitionKey(tp))
+	}
+}
+
+func topicPartitionKey(tp kafka.TopicPartition) partitionKey {
+	key := partitionKey{partition: tp.Partition}
+	if tp.Topic != nil {
+		key.topic = *tp.Topic
+	}
+	return key
+}
@@ -0,0 +1,89 @@
+package consumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func topicPartition(partition int32, offset kafka.Offset) kafka.TopicPartition {
+	topic := "payments.updated"
+	return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+}
+
+func TestOffsetTrackerWaitsForEarlierMessages(t *testing.T) {
+	assert := assert.New(t)
+	tracker := newOffsetTracker()
+	for offset := kafka.Offset(5); offset <= 7; offset++ {
+		tracker.Add(topicPartition(0, offset))
+	}
+
+	tracker.Done(topicPartition(0, 6))
+	tracker.Done(topicPartition(0, 7))
+	assert.Empty(tracker.Committable())
+	assert.Equal(1, tracker.InFlight())
+
+	tracker.Done(topicPartition(0, 5))
+	committable := tracker.Committable()
+	assert.Len(committable, 1)
+	assert.Equal(kafka.Offset(8), committable[0].Offset)
+	assert.Equal(0, tracker.InFlight())
+
+	tracker.Committed(committable)
+	assert.Empty(tracker.Committable())
+}
+
+func TestOffsetTrackerPartitions(t *testing.T) {
+	assert := assert.New(t)
+	tracker := newOffsetTracker()
+	tracker.Add(topicPartition(0, 10))
+	tracker.Add(topicPartition(1, 3))
+	tracker.Add(topicPartition(1, 4))
+
+	tracker.Done(topicPartition(1, 3))
+	committable := tracker.Committable()
+
+	assert.Len(committable, 1)
+	assert.Equal(int32(1), committable[0].Partition)
+	assert.Equal(kafka.Offset(4), committable[0].Offset)
+	assert.Equal(2, tracker.InFlight())
+}
+
+func TestOffsetTrackerRewind(t *testing.T) {
+	assert := assert.New(t)
+	tracker := newOffsetTracker()
+	tracker.Add(topicPartition(0, 10))
+	tracker.Add(topicPartition(0, 11))
+
+	tracker.Add(topicPartition(0, 10))
+	tracker.Done(topicPartition(0, 10))
+
+	committable := tracker.Committable()
+	assert.Len(committable, 1)
+	assert.Equal(kafka.Offset(11), committable[0].Offset)
+	assert.Equal(0, tracker.InFlight())
+}
+
+func TestOffsetTrackerDrop(t *testing.T) {
+	assert := assert.New(t)
+	tracker := newOffsetTracker()
+	tracker.Add(topicPartition(0, 10))
+	tracker.Add(topicPartition(1, 3))
+	tracker.Add(topicPartition(1, 4))
+	tracker.Done(topicPartition(1, 3))
+
+	revoked := []kafka.TopicPartition{topicPartition(1, kafka.OffsetInvalid)}
+	assert.Equal(1, tracker.InFlightOf(revoked))
+	assert.True(tracker.Tracks(topicPartition(1, 4)))
+	assert.False(tracker.Tracks(topicPartition(1, 3)), "handled messages are not tracked")
+
+	tracker.Drop(revoked)
+	assert.Equal(0, tracker.InFlightOf(revoked))
+	assert.False(tracker.Tracks(topicPartition(1, 4)))
+	tracker.Done(topicPartition(1, 4))
+
+	committable := tracker.Committable()
+	assert.Empty(committable)
+	assert.True(tracker.Tracks(topicPartition(0, 10)))
+	assert.Equal(1, tracker.InFlight())
+}
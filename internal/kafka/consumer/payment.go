@@ -1,58 +1,299 @@
 package consumer
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"log"
 	"payment-payments-api/internal/config"
 	"payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/kafka/producer"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/util"
+	"sync"
+	"time"
 )
 
+// pollTimeout bounds each read so the loop notices a cancelled context.
+const pollTimeout = 500 * time.Millisecond
+
+// drainPollInterval is how often a revoke checks whether the messages of the
+// revoked partitions were handled.
+const drainPollInterval = 10 * time.Millisecond
+
 type Consumer interface {
-	Consume(s *services.Services)
+	Consume(ctx context.Context, s *services.Services) error
+}
+
+// kafkaConsumer is the part of *kafka.Consumer the payment consumer uses.
+type kafkaConsumer interface {
+	Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Close() error
 }
 
-func NewPaymentConsumer(cfg *config.Config) (*paymentConsumer, error) {
+type paymentUpdater interface {
+	UpdatePayment(payment dto.PaymentResponse) error
+}
+
+func NewPaymentConsumer(cfg *config.Config, deadLetterProducer producer.DeadLetterProducer) (*paymentConsumer, error) {
 	c, err := kafka.NewConsumer(&kafka.ConfigMap{
 		"bootstrap.servers": cfg.BootstrapServers,
 		"group.id":          cfg.GroupID,
 		"auto.offset.reset": "earliest",
+		// Offsets are committed by hand once a message is handled, so a crash
+		// mid-update redelivers it instead of losing it.
+		"enable.auto.commit": false,
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &paymentConsumer{consumer: c, topic: cfg.ConsumerTopic}, nil
+	return &paymentConsumer{
+		consumer:           c,
+		topic:              cfg.ConsumerTopic,
+		deadLetterProducer: deadLetterProducer,
+		retryAttempts:      cfg.ConsumerRetryAttempts,
+		retryBackoff:       cfg.ConsumerRetryBackoff,
+		maxBackoff:         cfg.ConsumerMaxBackoff,
+		workers:            cfg.ConsumerWorkers,
+		queueSize:          cfg.ConsumerQueueSize,
+		commitInterval:     cfg.ConsumerCommitInterval,
+		drainTimeout:       cfg.ConsumerDrainTimeout,
+	}, nil
 }
 
 type paymentConsumer struct {
-	consumer *kafka.Consumer
-	topic    string
+	consumer           kafkaConsumer
+	topic              string
+	deadLetterProducer producer.DeadLetterProducer
+	retryAttempts      int
+	retryBackoff       time.Duration
+	maxBackoff         time.Duration
+	workers            int
+	queueSize          int
+	commitInterval     time.Duration
+	drainTimeout       time.Duration
 }
 
-func (c *paymentConsumer) Consume(s *services.Services) {
-	defer c.consumer.Close()
+// Consume handles messages until ctx is done and then leaves the consumer
+// group. Messages are spread over the workers by payment ID and handled in
+// parallel; reading pauses while the chosen worker's queue is full.
+//
+// Offsets are committed every commit interval, when partitions are revoked
+// and once more on the way out, but never past a message that was neither
+// applied nor dead-lettered, so every update is delivered at least once.
+func (c *paymentConsumer) Consume(ctx context.Context, s *services.Services) error {
+	return c.consume(ctx, s.Payment)
+}
 
-	err := c.consumer.Subscribe(c.topic, nil)
+func (c *paymentConsumer) consume(ctx context.Context, payments paymentUpdater) error {
+	offsets := newOffsetTracker()
+	err := c.consumer.Subscribe(c.topic, c.rebalance(ctx, offsets))
 	if err != nil {
-		log.Fatalf("Error subscribing to topic: %v", err)
+		c.consumer.Close()
+		return fmt.Errorf("error subscribing to topic: %w", err)
 	}
 
-	for {
-		msg, err := c.consumer.ReadMessage(-1)
-		if err == nil {
-			var message dto.PaymentResponse
-			err := json.Unmarshal(msg.Value, &message)
-			if err != nil {
-				log.Printf("Error unmarshalling message: %v", err)
-				continue
+	metrics.Set("inFlight", expvar.Func(func() any { return offsets.InFlight() }))
+
+	workers := make([]*worker, max(c.workers, 1))
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = newWorker(c.queueSize)
+		workers[i].publish(workerName(i))
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			c.work(ctx, w, payments, offsets)
+		}(workers[i])
+	}
+
+	stopCommitting := make(chan struct{})
+	committerDone := make(chan struct{})
+	go func() {
+		defer close(committerDone)
+		c.commitEvery(stopCommitting, offsets)
+	}()
+
+	for ctx.Err() == nil {
+		msg, err := c.consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			var kafkaErr kafka.Error
+			if !errors.As(err, &kafkaErr) || kafkaErr.Code() != kafka.ErrTimedOut {
+				log.Printf("Consumer error: %v (%v)\n", err, msg)
+			}
+			continue
+		}
+		offsets.Add(msg.TopicPartition)
+		workers[route(msg, len(workers))].queue <- msg
+	}
+
+	for _, w := range workers {
+		close(w.queue)
+	}
+	wg.Wait()
+	close(stopCommitting)
+	<-committerDone
+	c.commit(offsets)
+
+	return c.consumer.Close()
+}
+
+// work handles the messages of w until its queue is closed. A message that
+// could not be dead-lettered is retried until ctx is done; one still failing
+// then stays uncommitted, and the rest of the queue is left for redelivery
+// so updates of a payment are not applied out of order. Messages of revoked
+// partitions are skipped: their new owner reads them again.
+func (c *paymentConsumer) work(ctx context.Context, w *worker, payments paymentUpdater, offsets *offsetTracker) {
+	stalled := false
+	for msg := range w.queue {
+		if stalled || !offsets.Tracks(msg.TopicPartition) {
+			continue
+		}
+
+		for attempt := 1; ; attempt++ {
+			start := time.Now()
+			err := c.handle(ctx, payments, msg)
+			w.observe(start, err)
+			if err == nil {
+				offsets.Done(msg.TopicPartition)
+				break
+			}
+			if ctx.Err() != nil {
+				log.Printf("Leaving message %v uncommitted: %v", msg.TopicPartition, err)
+				stalled = true
+				break
 			}
-			s.Payment.UpdatePayment(message)
+			log.Printf("Message %v not handled, retrying: %v", msg.TopicPartition, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(util.Backoff(c.retryBackoff, c.maxBackoff, attempt)):
+			}
+		}
+	}
+}
 
-		} else {
-			log.Printf("Consumer error: %v (%v)\n", err, msg)
+// rebalance returns the callback Kafka runs, from ReadMessage, when
+// partitions are assigned or revoked. Before revoked partitions go to another
+// consumer, their messages in flight get up to the drain timeout to be
+// handled, the handled offsets are committed and the partitions are dropped.
+func (c *paymentConsumer) rebalance(ctx context.Context, offsets *offsetTracker) kafka.RebalanceCb {
+	return func(_ *kafka.Consumer, event kafka.Event) error {
+		revoked, ok := event.(kafka.RevokedPartitions)
+		if !ok {
+			return nil
 		}
+		c.drain(ctx, offsets, revoked.Partitions)
+		c.commit(offsets)
+		offsets.Drop(revoked.Partitions)
+		return nil
+	}
+}
+
+// drain waits until the messages of partitions were handled, the drain
+// timeout passed or ctx is done.
+func (c *paymentConsumer) drain(ctx context.Context, offsets *offsetTracker, partitions []kafka.TopicPartition) {
+	deadline := time.Now().Add(c.drainTimeout)
+	for {
+		inFlight := offsets.InFlightOf(partitions)
+		if inFlight == 0 {
+			return
+		}
+		if ctx.Err() != nil || !time.Now().Before(deadline) {
+			log.Printf("Revoking partitions %v with %d messages in flight", partitions, inFlight)
+			return
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// commitEvery commits the handled offsets on every commit interval until
+// stop is closed.
+func (c *paymentConsumer) commitEvery(stop <-chan struct{}, offsets *offsetTracker) {
+	ticker := time.NewTicker(c.commitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.commit(offsets)
+		}
+	}
+}
+
+func (c *paymentConsumer) commit(offsets *offsetTracker) {
+	committable := offsets.Committable()
+	if len(committable) == 0 {
+		return
+	}
+	if _, err := c.consumer.CommitOffsets(committable); err != nil {
+		log.Printf("Error committing offsets %v: %v", committable, err)
+		return
+	}
+	offsets.Committed(committable)
+}
+
+// handle applies msg, retrying with backoff up to the configured attempts.
+// Messages that cannot be decoded or keep failing go to the dead-letter topic.
+// It returns an error only when msg was neither applied nor dead-lettered and
+// must not be committed, which is also the case when ctx is done mid-retry.
+func (c *paymentConsumer) handle(ctx context.Context, payments paymentUpdater, msg *kafka.Message) error {
+	var message dto.PaymentResponse
+	err := json.Unmarshal(msg.Value, &message)
+	if err != nil {
+		return c.deadLetter(msg, fmt.Errorf("error unmarshalling message: %w", err), 0)
+	}
+
+	attempt := 1
+	for ; ; attempt++ {
+		err = payments.UpdatePayment(message)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, services.DuplicatePaymentEvent) {
+			log.Printf("Skipped redelivered event %s of payment %s", message.EventID, message.PaymentID)
+			return nil
+		}
+		var transitionErr *enums.TransitionError
+		if errors.As(err, &transitionErr) {
+			log.Printf("Rejected update for payment %s: %v", message.PaymentID, err)
+			return nil
+		}
+		if attempt >= c.retryAttempts || !retryable(err) {
+			break
+		}
+		log.Printf("Error updating payment %s (attempt %d): %v", message.PaymentID, attempt, err)
+		timer := time.NewTimer(util.Backoff(c.retryBackoff, c.maxBackoff, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("error updating payment: %w", err)
+		case <-timer.C:
+		}
+	}
+
+	return c.deadLetter(msg, fmt.Errorf("error updating payment: %w", err), attempt)
+}
+
+// retryable reports whether err may succeed on a later attempt.
+func retryable(err error) bool {
+	return !errors.Is(err, enums.InvalidStatus) && !errors.Is(err, services.InvalidPaymentID)
+}
+
+func (c *paymentConsumer) deadLetter(msg *kafka.Message, cause error, attempts int) error {
+	log.Printf("Dead-lettering message %v: %v", msg.TopicPartition, cause)
+	err := c.deadLetterProducer.Produce(msg, cause, attempts)
+	if err != nil {
+		return fmt.Errorf("error dead-lettering message: %w", err)
 	}
+	return nil
 }
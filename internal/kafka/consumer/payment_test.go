@@ -0,0 +1,435 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/services"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKafkaConsumer replays messages as a partition log, calling idle on
+// every read past its end and beforeRead[i] before returning message i.
+type fakeKafkaConsumer struct {
+	mu           sync.Mutex
+	messages     []*kafka.Message
+	next         int
+	idle         func()
+	beforeRead   map[int]func()
+	rebalanceCb  kafka.RebalanceCb
+	subscribeErr error
+	committed    map[int32]kafka.Offset
+	closed       bool
+}
+
+func (f *fakeKafkaConsumer) Subscribe(topic string, rebalanceCb kafka.RebalanceCb) error {
+	f.rebalanceCb = rebalanceCb
+	return f.subscribeErr
+}
+
+func (f *fakeKafkaConsumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	f.mu.Lock()
+	if f.next < len(f.messages) {
+		i := f.next
+		f.next++
+		f.mu.Unlock()
+		if hook, ok := f.beforeRead[i]; ok {
+			hook()
+		}
+		return f.messages[i], nil
+	}
+	f.mu.Unlock()
+
+	f.idle()
+	time.Sleep(time.Millisecond)
+	return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+}
+
+func (f *fakeKafkaConsumer) CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.committed == nil {
+		f.committed = map[int32]kafka.Offset{}
+	}
+	for _, tp := range offsets {
+		f.committed[tp.Partition] = tp.Offset
+	}
+	return offsets, nil
+}
+
+func (f *fakeKafkaConsumer) committedOffset(partition int32) (kafka.Offset, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	offset, ok := f.committed[partition]
+	return offset, ok
+}
+
+// revoke runs the rebalance callback as Kafka does when partitions are
+// taken away from the consumer.
+func (f *fakeKafkaConsumer) revoke(partitions ...int32) {
+	var revoked kafka.RevokedPartitions
+	for _, partition := range partitions {
+		revoked.Partitions = append(revoked.Partitions, topicPartition(partition, kafka.OffsetInvalid))
+	}
+	f.rebalanceCb(nil, revoked)
+}
+
+func (f *fakeKafkaConsumer) Close() error {
+	f.closed = true
+	return nil
+}
+
+type fakePaymentUpdater struct {
+	mu      sync.Mutex
+	results map[string][]error
+	updates []dto.PaymentResponse
+	held    map[string]chan struct{}
+	started chan string
+}
+
+// UpdatePayment fails with the next error queued for the event, if any.
+// Updates of held events report on started and wait until released.
+func (f *fakePaymentUpdater) UpdatePayment(payment dto.PaymentResponse) error {
+	if release, ok := f.held[payment.EventID]; ok {
+		f.started <- payment.EventID
+		<-release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, payment)
+	results := f.results[payment.EventID]
+	if len(results) == 0 {
+		return nil
+	}
+	f.results[payment.EventID] = results[1:]
+	return results[0]
+}
+
+func (f *fakePaymentUpdater) eventsOf(paymentID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []string
+	for _, update := range f.updates {
+		if update.PaymentID == paymentID {
+			events = append(events, update.EventID)
+		}
+	}
+	return events
+}
+
+type fakeDeadLetterProducer struct {
+	mu      sync.Mutex
+	results []error
+	letters []kafka.Offset
+}
+
+func (f *fakeDeadLetterProducer) Produce(message *kafka.Message, cause error, attempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.letters = append(f.letters, message.TopicPartition.Offset)
+	if len(f.results) == 0 {
+		return nil
+	}
+	err := f.results[0]
+	f.results = f.results[1:]
+	return err
+}
+
+func (f *fakeDeadLetterProducer) sorted() []kafka.Offset {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	letters := append([]kafka.Offset(nil), f.letters...)
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+	return letters
+}
+
+func paymentMessage(offset kafka.Offset, update dto.PaymentResponse) *kafka.Message {
+	return partitionMessage(0, offset, update)
+}
+
+func partitionMessage(partition int32, offset kafka.Offset, update dto.PaymentResponse) *kafka.Message {
+	value, _ := json.Marshal(update)
+	return &kafka.Message{TopicPartition: topicPartition(partition, offset), Value: value}
+}
+
+func newTestConsumer(kc *fakeKafkaConsumer, deadLetters *fakeDeadLetterProducer) *paymentConsumer {
+	return &paymentConsumer{
+		consumer:           kc,
+		topic:              "payments.updated",
+		deadLetterProducer: deadLetters,
+		retryAttempts:      2,
+		retryBackoff:       time.Millisecond,
+		maxBackoff:         time.Millisecond,
+		workers:            4,
+		queueSize:          2,
+		commitInterval:     time.Millisecond,
+		drainTimeout:       time.Second,
+	}
+}
+
+// runConsumer consumes messages until every one of them was committed, or
+// gives up after a second.
+func runConsumer(messages []*kafka.Message, payments *fakePaymentUpdater,
+	deadLetters *fakeDeadLetterProducer) (*fakeKafkaConsumer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	kc := &fakeKafkaConsumer{messages: messages}
+	kc.idle = func() {
+		kc.mu.Lock()
+		defer kc.mu.Unlock()
+		if kc.committed[0] == messages[len(messages)-1].TopicPartition.Offset+1 {
+			cancel()
+		}
+	}
+	return kc, newTestConsumer(kc, deadLetters).consume(ctx, payments)
+}
+
+func TestConsumeCommitsAfterUpdate(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{}
+	deadLetters := &fakeDeadLetterProducer{}
+	messages := []*kafka.Message{
+		paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1", Status: "Approved"}),
+		paymentMessage(11, dto.PaymentResponse{EventID: "ev-2", PaymentID: "p-2", Status: "Failed"}),
+	}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Equal(kafka.Offset(12), kc.committed[0])
+	assert.Equal([]string{"ev-1"}, payments.eventsOf("p-1"))
+	assert.Equal([]string{"ev-2"}, payments.eventsOf("p-2"))
+	assert.Empty(deadLetters.letters)
+	assert.True(kc.closed)
+}
+
+func TestConsumeRetriesBeforeCommit(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{"ev-1": {errors.New("connection reset")}}}
+	deadLetters := &fakeDeadLetterProducer{}
+	messages := []*kafka.Message{paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1"})}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Equal([]string{"ev-1", "ev-1"}, payments.eventsOf("p-1"))
+	assert.Equal(kafka.Offset(11), kc.committed[0])
+	assert.Empty(deadLetters.letters)
+}
+
+func TestConsumeCommitsDeadLetteredMessages(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{"ev-1": {services.InvalidPaymentID}}}
+	deadLetters := &fakeDeadLetterProducer{}
+	malformed := paymentMessage(11, dto.PaymentResponse{})
+	malformed.Value = []byte("{")
+	messages := []*kafka.Message{
+		paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "not-a-uuid"}),
+		malformed,
+	}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Len(payments.eventsOf("not-a-uuid"), 1, "invalid payment IDs are not retried")
+	assert.Equal([]kafka.Offset{10, 11}, deadLetters.sorted())
+	assert.Equal(kafka.Offset(12), kc.committed[0])
+}
+
+func TestConsumeRetriesWhenDeadLetterFails(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{
+		"ev-1": {services.InvalidPaymentID, services.InvalidPaymentID},
+	}}
+	deadLetters := &fakeDeadLetterProducer{results: []error{errors.New("broker unavailable")}}
+	messages := []*kafka.Message{
+		paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "not-a-uuid"}),
+		paymentMessage(11, dto.PaymentResponse{EventID: "ev-2", PaymentID: "p-2"}),
+	}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Equal([]kafka.Offset{10, 10}, deadLetters.sorted())
+	assert.Equal(kafka.Offset(12), kc.committed[0])
+}
+
+func TestConsumeLeavesFailingMessageUncommitted(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{
+		"ev-2": {services.InvalidPaymentID, services.InvalidPaymentID, services.InvalidPaymentID},
+	}}
+	deadLetters := &fakeDeadLetterProducer{results: []error{
+		errors.New("broker unavailable"), errors.New("broker unavailable"), errors.New("broker unavailable"),
+	}}
+	messages := []*kafka.Message{
+		paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1"}),
+		paymentMessage(11, dto.PaymentResponse{EventID: "ev-2", PaymentID: "not-a-uuid"}),
+		paymentMessage(12, dto.PaymentResponse{EventID: "ev-3", PaymentID: "p-3"}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kc := &fakeKafkaConsumer{messages: messages, idle: cancel}
+
+	err := newTestConsumer(kc, deadLetters).consume(ctx, payments)
+
+	assert.Nil(err)
+	assert.Equal(kafka.Offset(11), kc.committed[0], "nothing is committed past an unhandled message")
+	assert.Equal([]string{"ev-3"}, payments.eventsOf("p-3"), "later messages are still handled")
+}
+
+func TestConsumeCommitsRedeliveredEvents(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{"ev-1": {nil, services.DuplicatePaymentEvent}}}
+	deadLetters := &fakeDeadLetterProducer{}
+	update := dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1", Status: "Approved"}
+	messages := []*kafka.Message{paymentMessage(10, update), paymentMessage(11, update)}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Len(payments.eventsOf("p-1"), 2, "duplicates are not retried")
+	assert.Equal(kafka.Offset(12), kc.committed[0])
+	assert.Empty(deadLetters.letters)
+}
+
+func TestConsumeCommitsRejectedTransitions(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{
+		"ev-1": {&enums.TransitionError{From: enums.Approved, To: enums.Pending}},
+	}}
+	deadLetters := &fakeDeadLetterProducer{}
+	messages := []*kafka.Message{paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1"})}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Len(payments.eventsOf("p-1"), 1)
+	assert.Equal(kafka.Offset(11), kc.committed[0])
+	assert.Empty(deadLetters.letters)
+}
+
+func TestConsumeKeepsPaymentOrder(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{}
+	deadLetters := &fakeDeadLetterProducer{}
+	var messages []*kafka.Message
+	want := map[string][]string{}
+	for i := 0; i < 60; i++ {
+		paymentID := fmt.Sprintf("p-%d", i%6)
+		eventID := fmt.Sprintf("ev-%d", i)
+		want[paymentID] = append(want[paymentID], eventID)
+		messages = append(messages, paymentMessage(kafka.Offset(i), dto.PaymentResponse{EventID: eventID, PaymentID: paymentID}))
+	}
+
+	kc, err := runConsumer(messages, payments, deadLetters)
+
+	assert.Nil(err)
+	assert.Equal(kafka.Offset(60), kc.committed[0])
+	for paymentID, events := range want {
+		assert.Equal(events, payments.eventsOf(paymentID))
+	}
+}
+
+func TestConsumeRevokeDrainsPartition(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{}
+	messages := []*kafka.Message{
+		partitionMessage(1, 20, dto.PaymentResponse{EventID: "ev-a", PaymentID: "p-a"}),
+		partitionMessage(1, 21, dto.PaymentResponse{EventID: "ev-b", PaymentID: "p-b"}),
+		partitionMessage(0, 10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1"}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	kc := &fakeKafkaConsumer{messages: messages}
+	var committedOnRevoke kafka.Offset
+	kc.beforeRead = map[int]func(){2: func() {
+		kc.revoke(1)
+		committedOnRevoke, _ = kc.committedOffset(1)
+	}}
+	kc.idle = func() {
+		if offset, _ := kc.committedOffset(0); offset == 11 {
+			cancel()
+		}
+	}
+
+	err := newTestConsumer(kc, &fakeDeadLetterProducer{}).consume(ctx, payments)
+
+	assert.Nil(err)
+	assert.Equal(kafka.Offset(22), committedOnRevoke, "revoked partitions are committed before they are handed over")
+	assert.Equal([]string{"ev-a"}, payments.eventsOf("p-a"))
+	assert.Equal([]string{"ev-b"}, payments.eventsOf("p-b"))
+	assert.Equal([]string{"ev-1"}, payments.eventsOf("p-1"))
+}
+
+func TestConsumeRevokeSkipsUndrainedMessages(t *testing.T) {
+	assert := assert.New(t)
+	release := make(chan struct{})
+	payments := &fakePaymentUpdater{held: map[string]chan struct{}{"ev-a": release}, started: make(chan string, 1)}
+	messages := []*kafka.Message{
+		partitionMessage(1, 20, dto.PaymentResponse{EventID: "ev-a", PaymentID: "p-a"}),
+		partitionMessage(1, 21, dto.PaymentResponse{EventID: "ev-b", PaymentID: "p-a"}),
+		paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1"}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	kc := &fakeKafkaConsumer{messages: messages}
+	kc.beforeRead = map[int]func(){2: func() {
+		<-payments.started
+		kc.revoke(1)
+		close(release)
+	}}
+	kc.idle = func() {
+		if offset, _ := kc.committedOffset(0); offset == 11 {
+			cancel()
+		}
+	}
+	c := newTestConsumer(kc, &fakeDeadLetterProducer{})
+	c.drainTimeout = 20 * time.Millisecond
+
+	err := c.consume(ctx, payments)
+
+	assert.Nil(err)
+	_, committed := kc.committedOffset(1)
+	assert.False(committed, "nothing of a partition revoked mid-update is committed")
+	assert.Equal([]string{"ev-a"}, payments.eventsOf("p-a"), "queued messages of revoked partitions are skipped")
+	assert.Equal([]string{"ev-1"}, payments.eventsOf("p-1"))
+}
+
+func TestHandleStopsRetryingWhenCancelled(t *testing.T) {
+	assert := assert.New(t)
+	payments := &fakePaymentUpdater{results: map[string][]error{"ev-1": {errors.New("connection reset")}}}
+	deadLetters := &fakeDeadLetterProducer{}
+	c := newTestConsumer(&fakeKafkaConsumer{}, deadLetters)
+	c.retryBackoff = time.Hour
+	c.maxBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := c.handle(ctx, payments, paymentMessage(10, dto.PaymentResponse{EventID: "ev-1", PaymentID: "p-1"}))
+
+	assert.EqualError(err, "error updating payment: connection reset")
+	assert.Less(time.Since(start), time.Second)
+	assert.Len(payments.eventsOf("p-1"), 1)
+	assert.Empty(deadLetters.letters, "a cancelled message is redelivered, not dead-lettered")
+}
+
+func TestConsumeSubscribeError(t *testing.T) {
+	assert := assert.New(t)
+	kc := &fakeKafkaConsumer{subscribeErr: errors.New("unknown topic")}
+	c := &paymentConsumer{consumer: kc, topic: "payments.updated"}
+
+	err := c.consume(context.Background(), &fakePaymentUpdater{})
+
+	assert.EqualError(err, "error subscribing to topic: unknown topic")
+	assert.True(kc.closed)
+}
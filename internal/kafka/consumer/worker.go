@@ -0,0 +1,68 @@
+package consumer
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"hash/fnv"
+	"time"
+)
+
+// metrics is published through expvar as "paymentConsumer": the number of
+// messages in flight and one map per worker.
+var metrics = expvar.NewMap("paymentConsumer")
+
+// worker handles the messages routed to it one at a time, in the order they
+// were read. Every update of a payment goes to the same worker, which keeps
+// them in order while different payments are handled in parallel.
+type worker struct {
+	queue chan *kafka.Message
+
+	processed    expvar.Int
+	failed       expvar.Int
+	processingMs expvar.Int
+}
+
+func newWorker(queueSize int) *worker {
+	return &worker{queue: make(chan *kafka.Message, queueSize)}
+}
+
+// publish exposes the worker's counters and queue length as metrics.
+func (w *worker) publish(name string) {
+	m := new(expvar.Map).Init()
+	m.Set("processed", &w.processed)
+	m.Set("failed", &w.failed)
+	m.Set("processingMs", &w.processingMs)
+	m.Set("queued", expvar.Func(func() any { return len(w.queue) }))
+	metrics.Set(name, m)
+}
+
+func (w *worker) observe(start time.Time, err error) {
+	w.processingMs.Add(time.Since(start).Milliseconds())
+	if err != nil {
+		w.failed.Add(1)
+	} else {
+		w.processed.Add(1)
+	}
+}
+
+// route picks the worker for msg by hashing its payment ID. Messages whose
+// payload cannot be read fall back to the Kafka key.
+func route(msg *kafka.Message, workers int) int {
+	var update struct {
+		PaymentID string `json:"paymentID"`
+	}
+	key := string(msg.Key)
+	if err := json.Unmarshal(msg.Value, &update); err == nil && update.PaymentID != "" {
+		key = update.PaymentID
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+func workerName(i int) string {
+	return fmt.Sprintf("worker-%d", i)
+}
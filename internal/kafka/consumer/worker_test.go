@@ -0,0 +1,26 @@
+package consumer
+
+import (
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"payment-payments-api/internal/kafka/dto"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	assert := assert.New(t)
+	first := paymentMessage(1, dto.PaymentResponse{PaymentID: "p-1"})
+	again := paymentMessage(2, dto.PaymentResponse{PaymentID: "p-1"})
+	byKey := &kafka.Message{Key: []byte("p-1"), Value: []byte("not json")}
+
+	assert.Equal(route(first, 8), route(again, 8))
+	assert.Equal(route(first, 8), route(byKey, 8))
+	assert.Equal(0, route(first, 1))
+
+	workers := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		workers[route(paymentMessage(kafka.Offset(i), dto.PaymentResponse{PaymentID: fmt.Sprintf("p-%d", i)}), 4)] = true
+	}
+	assert.Len(workers, 4, "payments are spread over every worker")
+}
@@ -1,38 +1,74 @@
 package dto
 
 import (
-	"payment-payments-api/internal/api/dto"
+	"encoding/json"
 	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
 )
 
-func MapPaymentRequestToPaymentRequest(dto dto.PaymentRequest) PaymentRequest {
-	return PaymentRequest{
-		CardID:      dto.CardID,
-		CVC:         dto.CVC,
-		ExpiredDate: dto.ExpiredDate,
-		Amount:      dto.Amount,
-		Currency:    dto.Currency,
-		Merchant:    dto.Merchant,
-	}
-}
-
 type PaymentRequest struct {
 	PaymentID     string              `json:"paymentId"`
 	TransactionID string              `json:"transactionId"`
 	Status        enums.PaymentStatus `json:"status"`
-	CardID        string              `json:"cardId"`
-	CVC           string              `json:"cvc"`
-	ExpiredDate   string              `json:"expiredDate"`
-	Amount        float64             `json:"amount"`
-	Type          enums.PaymentType   `json:"type"`
-	Currency      string              `json:"currency"`
-	Merchant      string              `json:"merchant"`
+	// EncryptedCard is the base64 AES-GCM sealed card number, expiry and CVC,
+	// readable only with the key shared with the bank adapter. The payment ID
+	// is used as additional authenticated data.
+	EncryptedCard string `json:"encryptedCard,omitempty"`
+	// Amount and Currency keep the contract the bank adapter was built on:
+	// a decimal number and an ISO 4217 code. AmountMoney carries the same
+	// amount as {"value","currency"}. Set all three with SetAmount.
+	Amount      json.Number       `json:"amount"`
+	Currency    string            `json:"currency"`
+	AmountMoney money.Money       `json:"amountMoney"`
+	Type        enums.PaymentType `json:"type"`
+	Merchant    string            `json:"merchant"`
+	// RefundReference is the ID of the refund record a Refund message was
+	// created for; the bank echoes it back on the matching update.
+	RefundReference string `json:"refundReference,omitempty"`
+}
+
+// SetAmount fills in the amount in both its legacy and Money forms.
+func (r *PaymentRequest) SetAmount(amount money.Money) {
+	r.Amount = amount.Number()
+	r.Currency = amount.Currency
+	r.AmountMoney = amount
+}
+
+// UnmarshalJSON also reads outbox payloads stored while amount was encoded
+// as a Money object.
+func (r *PaymentRequest) UnmarshalJSON(data []byte) error {
+	type plain PaymentRequest
+	var raw struct {
+		plain
+		Amount json.RawMessage `json:"amount"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = PaymentRequest(raw.plain)
+
+	if len(raw.Amount) > 0 && raw.Amount[0] == '{' {
+		var amount money.Money
+		if err := json.Unmarshal(raw.Amount, &amount); err != nil {
+			return err
+		}
+		r.SetAmount(amount)
+		return nil
+	}
+	if len(raw.Amount) > 0 && string(raw.Amount) != "null" {
+		return json.Unmarshal(raw.Amount, &r.Amount)
+	}
+	return nil
 }
 
 type PaymentResponse struct {
-	PaymentID     string `json:"paymentID"`
-	TransactionID string `json:"transactionID"`
-	Status        string `json:"status"`
-	Msg           string `json:"msg"`
-	RefundID      string `json:"refundID"`
+	// EventID identifies the bank event behind the update. Together with the
+	// payment ID it makes redelivered updates detectable.
+	EventID         string `json:"eventID"`
+	PaymentID       string `json:"paymentID"`
+	TransactionID   string `json:"transactionID"`
+	Status          string `json:"status"`
+	Msg             string `json:"msg"`
+	RefundID        string `json:"refundID"`
+	RefundReference string `json:"refundReference"`
 }
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+	"testing"
+)
+
+func TestPaymentRequestAmountJSON(t *testing.T) {
+	assert := assert.New(t)
+	request := PaymentRequest{PaymentID: "p-1", Status: enums.Pending, Type: enums.Payment}
+	request.SetAmount(money.New(1050, "USD"))
+
+	body, err := json.Marshal(request)
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(body, &fields)
+
+	assert.Nil(err)
+	assert.Equal(`10.50`, string(fields["amount"]))
+	assert.Equal(`"USD"`, string(fields["currency"]))
+	assert.Equal(`{"value":"10.50","currency":"USD"}`, string(fields["amountMoney"]))
+
+	var decoded PaymentRequest
+	assert.Nil(json.Unmarshal(body, &decoded))
+	assert.Equal(request, decoded)
+}
+
+func TestPaymentRequestLegacyMoneyAmount(t *testing.T) {
+	assert := assert.New(t)
+
+	var request PaymentRequest
+	err := json.Unmarshal([]byte(`{"paymentId":"p-1","status":"Pending","type":"Refund","amount":{"value":"1.005","currency":"KWD"}}`), &request)
+
+	assert.Nil(err)
+	assert.Equal("p-1", request.PaymentID)
+	assert.Equal(json.Number("1.005"), request.Amount)
+	assert.Equal("KWD", request.Currency)
+	assert.Equal(money.New(1005, "KWD"), request.AmountMoney)
+}
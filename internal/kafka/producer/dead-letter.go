@@ -0,0 +1,107 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"log"
+	"payment-payments-api/internal/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DeadLetterHeaderPrefix            = "x-dlq-"
+	DeadLetterHeaderError             = DeadLetterHeaderPrefix + "error"
+	DeadLetterHeaderAttempts          = DeadLetterHeaderPrefix + "attempts"
+	DeadLetterHeaderOriginalTopic     = DeadLetterHeaderPrefix + "original-topic"
+	DeadLetterHeaderOriginalPartition = DeadLetterHeaderPrefix + "original-partition"
+	DeadLetterHeaderOriginalOffset    = DeadLetterHeaderPrefix + "original-offset"
+	DeadLetterHeaderFailedAt          = DeadLetterHeaderPrefix + "failed-at"
+	DeadLetterHeaderReplays           = DeadLetterHeaderPrefix + "replays"
+)
+
+type DeadLetterProducer interface {
+	Produce(message *kafka.Message, cause error, attempts int) error
+}
+
+func NewDeadLetterProducer(cfg *config.Config) (*deadLetterProducer, error) {
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cfg.BootstrapServers})
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetterProducer{producer: p, topic: cfg.DeadLetterTopic}, nil
+}
+
+type deadLetterProducer struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+// Produce copies the original payload, key and headers of message to the
+// dead-letter topic, adding headers that describe why it failed.
+func (p *deadLetterProducer) Produce(message *kafka.Message, cause error, attempts int) error {
+	headers := StripDeadLetterHeaders(message.Headers)
+	headers = append(headers,
+		kafka.Header{Key: DeadLetterHeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: DeadLetterHeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: DeadLetterHeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+		kafka.Header{Key: DeadLetterHeaderReplays, Value: []byte(strconv.Itoa(DeadLetterReplays(message.Headers)))},
+	)
+	if tp := message.TopicPartition; tp.Topic != nil {
+		headers = append(headers,
+			kafka.Header{Key: DeadLetterHeaderOriginalTopic, Value: []byte(*tp.Topic)},
+			kafka.Header{Key: DeadLetterHeaderOriginalPartition, Value: []byte(strconv.Itoa(int(tp.Partition)))},
+			kafka.Header{Key: DeadLetterHeaderOriginalOffset, Value: []byte(tp.Offset.String())},
+		)
+	}
+
+	deliveryChan := make(chan kafka.Event)
+	defer close(deliveryChan)
+
+	err := p.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            message.Key,
+		Value:          message.Value,
+		Headers:        headers,
+	}, deliveryChan)
+	if err != nil {
+		return fmt.Errorf("failed to produce dead letter: %w", err)
+	}
+
+	e := <-deliveryChan
+	msg := e.(*kafka.Message)
+	if msg.TopicPartition.Error != nil {
+		return fmt.Errorf("dead letter delivery failed: %v", msg.TopicPartition.Error)
+	}
+
+	log.Printf("Dead-lettered message to %v", msg.TopicPartition)
+	return nil
+}
+
+func (p *deadLetterProducer) Close(ctx context.Context) error {
+	return closeProducer(ctx, p.producer)
+}
+
+// StripDeadLetterHeaders returns headers without the ones added by the dead-letter producer.
+func StripDeadLetterHeaders(headers []kafka.Header) []kafka.Header {
+	stripped := make([]kafka.Header, 0, len(headers))
+	for _, header := range headers {
+		if !strings.HasPrefix(header.Key, DeadLetterHeaderPrefix) {
+			stripped = append(stripped, header)
+		}
+	}
+	return stripped
+}
+
+// DeadLetterReplays returns how many times a message was replayed from the dead-letter topic.
+func DeadLetterReplays(headers []kafka.Header) int {
+	for _, header := range headers {
+		if header.Key == DeadLetterHeaderReplays {
+			replays, _ := strconv.Atoi(string(header.Value))
+			return replays
+		}
+	}
+	return 0
+}
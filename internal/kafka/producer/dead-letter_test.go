@@ -0,0 +1,54 @@
+package producer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStripDeadLetterHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  []kafka.Header
+		stripped []kafka.Header
+	}{
+		{"no headers", nil, []kafka.Header{}},
+		{"only dead-letter headers", []kafka.Header{
+			{Key: DeadLetterHeaderError, Value: []byte("boom")},
+			{Key: DeadLetterHeaderReplays, Value: []byte("1")},
+		}, []kafka.Header{}},
+		{"mixed headers keep their order", []kafka.Header{
+			{Key: "trace-id", Value: []byte("abc")},
+			{Key: DeadLetterHeaderAttempts, Value: []byte("3")},
+			{Key: "x-dl", Value: []byte("kept")},
+			{Key: DeadLetterHeaderOriginalTopic, Value: []byte("payments.updated")},
+			{Key: "content-type", Value: []byte("application/json")},
+		}, []kafka.Header{
+			{Key: "trace-id", Value: []byte("abc")},
+			{Key: "x-dl", Value: []byte("kept")},
+			{Key: "content-type", Value: []byte("application/json")},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.stripped, StripDeadLetterHeaders(test.headers))
+		})
+	}
+}
+
+func TestDeadLetterReplays(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []kafka.Header
+		replays int
+	}{
+		{"no header", []kafka.Header{{Key: "trace-id", Value: []byte("abc")}}, 0},
+		{"replayed", []kafka.Header{{Key: DeadLetterHeaderReplays, Value: []byte("2")}}, 2},
+		{"malformed", []kafka.Header{{Key: DeadLetterHeaderReplays, Value: []byte("two")}}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.replays, DeadLetterReplays(test.headers))
+		})
+	}
+}
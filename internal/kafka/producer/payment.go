@@ -1,6 +1,7 @@
 package producer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
@@ -37,6 +38,7 @@ func (p *paymentProducer) Produce(message dto.PaymentRequest) error {
 
 	err = p.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(message.PaymentID),
 		Value:          value,
 	}, deliveryChan)
 	if err != nil {
@@ -52,3 +54,7 @@ func (p *paymentProducer) Produce(message dto.PaymentRequest) error {
 	log.Printf("Delivered message to %v", msg.TopicPartition)
 	return nil
 }
+
+func (p *paymentProducer) Close(ctx context.Context) error {
+	return closeProducer(ctx, p.producer)
+}
@@ -0,0 +1,23 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"time"
+)
+
+const flushInterval = 100 * time.Millisecond
+
+// closeProducer waits for queued messages to be delivered until ctx is done,
+// then closes p. Messages still queued at that point are lost and reported.
+func closeProducer(ctx context.Context, p *kafka.Producer) error {
+	defer p.Close()
+
+	for pending := p.Len(); pending > 0; pending = p.Flush(int(flushInterval.Milliseconds())) {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%d messages not delivered: %w", pending, ctx.Err())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"log"
+	"payment-payments-api/internal/config"
+	"payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/kafka/producer"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/util"
+	"time"
+)
+
+type Relay interface {
+	Relay(ctx context.Context)
+}
+
+func NewOutboxRelay(cfg *config.Config, outboxRepository repositories.OutboxRepository,
+	paymentProducer producer.PaymentProducer) *outboxRelay {
+	return &outboxRelay{
+		outboxRepository: outboxRepository,
+		paymentProducer:  paymentProducer,
+		pollInterval:     cfg.OutboxPollInterval,
+		batchSize:        cfg.OutboxBatchSize,
+		lease:            cfg.OutboxLease,
+		retryBackoff:     cfg.OutboxRetryBackoff,
+		maxBackoff:       cfg.OutboxMaxBackoff,
+		maxAttempts:      cfg.OutboxMaxAttempts,
+	}
+}
+
+type outboxRelay struct {
+	outboxRepository repositories.OutboxRepository
+	paymentProducer  producer.PaymentProducer
+	pollInterval     time.Duration
+	batchSize        int
+	lease            time.Duration
+	retryBackoff     time.Duration
+	maxBackoff       time.Duration
+	maxAttempts      int
+}
+
+// Relay publishes pending outbox messages on every poll interval until ctx is done.
+func (r *outboxRelay) Relay(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.relayPending()
+		}
+	}
+}
+
+// relayPending publishes the claimed messages in order. Once a message of an
+// aggregate fails, the rest of that aggregate waits for its retry. After
+// maxAttempts a message is marked failed and its payload dropped.
+func (r *outboxRelay) relayPending() {
+	messages, err := r.outboxRepository.ClaimPendingOutboxMessages(time.Now(), r.lease, r.batchSize)
+	if err != nil {
+		log.Printf("Error claiming outbox messages: %v", err)
+		return
+	}
+
+	blocked := make(map[uuid.UUID]bool)
+	for _, message := range messages {
+		if blocked[message.AggregateID] {
+			continue
+		}
+		err = r.publish(message)
+		if err != nil {
+			blocked[message.AggregateID] = true
+			message.Attempts++
+			message.LastError = err.Error()
+			message.NextAttemptAt = time.Now().Add(util.Backoff(r.retryBackoff, r.maxBackoff, message.Attempts))
+			log.Printf("Error relaying outbox message %s (attempt %d): %v", message.ID, message.Attempts, err)
+			if r.maxAttempts > 0 && message.Attempts >= r.maxAttempts {
+				failedAt := time.Now()
+				message.FailedAt = &failedAt
+				message.Payload = nil
+				log.Printf("Giving up on outbox message %s of %s after %d attempts", message.ID, message.AggregateID, message.Attempts)
+			}
+		} else {
+			// Payment payloads carry the bank-sealed card and CVC, which must
+			// not outlive the delivery.
+			sentAt := time.Now()
+			message.SentAt = &sentAt
+			message.LastError = ""
+			message.Payload = nil
+		}
+
+		_, err = r.outboxRepository.UpdateOutboxMessage(message)
+		if err != nil {
+			log.Printf("Error updating outbox message %s: %v", message.ID, err)
+		}
+	}
+}
+
+func (r *outboxRelay) publish(message models.OutboxMessage) error {
+	var request dto.PaymentRequest
+	if err := json.Unmarshal(message.Payload, &request); err != nil {
+		return err
+	}
+	return r.paymentProducer.Produce(request)
+}
@@ -0,0 +1,109 @@
+package relay
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/money"
+	"testing"
+	"time"
+)
+
+// fakeOutboxRepository hands out claimed once and records every update.
+type fakeOutboxRepository struct {
+	repositories.OutboxRepository
+	claimed []models.OutboxMessage
+	updated []models.OutboxMessage
+}
+
+func (r *fakeOutboxRepository) ClaimPendingOutboxMessages(now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error) {
+	claimed := r.claimed
+	r.claimed = nil
+	return claimed, nil
+}
+
+func (r *fakeOutboxRepository) UpdateOutboxMessage(message models.OutboxMessage) (models.OutboxMessage, error) {
+	r.updated = append(r.updated, message)
+	return message, nil
+}
+
+// fakePaymentProducer fails every payment in fail and records the rest.
+type fakePaymentProducer struct {
+	fail     map[string]bool
+	produced []string
+}
+
+func (p *fakePaymentProducer) Produce(message dto.PaymentRequest) error {
+	if p.fail[message.TransactionID] {
+		return errors.New("broker unavailable")
+	}
+	p.produced = append(p.produced, message.TransactionID)
+	return nil
+}
+
+func outboxMessage(t *testing.T, aggregateID uuid.UUID, transactionID string) models.OutboxMessage {
+	request := dto.PaymentRequest{TransactionID: transactionID, Status: enums.Pending, Type: enums.Payment}
+	request.SetAmount(money.New(1000, "USD"))
+	message, err := models.NewOutboxMessage(aggregateID, request)
+	assert.Nil(t, err)
+	message.ID = uuid.New()
+	return message
+}
+
+func newTestRelay(repo *fakeOutboxRepository, producer *fakePaymentProducer) *outboxRelay {
+	return &outboxRelay{
+		outboxRepository: repo,
+		paymentProducer:  producer,
+		retryBackoff:     time.Second,
+		maxBackoff:       time.Minute,
+		maxAttempts:      3,
+	}
+}
+
+func TestRelayPendingStopsAggregateAtFirstFailure(t *testing.T) {
+	assert := assert.New(t)
+	first, second := uuid.New(), uuid.New()
+	repo := &fakeOutboxRepository{claimed: []models.OutboxMessage{
+		outboxMessage(t, first, "a1"),
+		outboxMessage(t, second, "b1"),
+		outboxMessage(t, first, "a2"),
+		outboxMessage(t, second, "b2"),
+	}}
+	producer := &fakePaymentProducer{fail: map[string]bool{"a1": true}}
+
+	newTestRelay(repo, producer).relayPending()
+
+	assert.Equal([]string{"b1", "b2"}, producer.produced)
+	assert.Len(repo.updated, 3, "a2 is left for the retry of a1")
+	failed := repo.updated[0]
+	assert.Equal(1, failed.Attempts)
+	assert.Equal("broker unavailable", failed.LastError)
+	assert.Nil(failed.SentAt)
+	assert.Nil(failed.FailedAt)
+	assert.NotNil(failed.Payload)
+	for _, sent := range repo.updated[1:] {
+		assert.NotNil(sent.SentAt)
+		assert.Nil(sent.Payload)
+	}
+}
+
+func TestRelayPendingGivesUpAfterMaxAttempts(t *testing.T) {
+	assert := assert.New(t)
+	message := outboxMessage(t, uuid.New(), "a1")
+	message.Attempts = 2
+	repo := &fakeOutboxRepository{claimed: []models.OutboxMessage{message}}
+	producer := &fakePaymentProducer{fail: map[string]bool{"a1": true}}
+
+	newTestRelay(repo, producer).relayPending()
+
+	assert.Len(repo.updated, 1)
+	failed := repo.updated[0]
+	assert.Equal(3, failed.Attempts)
+	assert.NotNil(failed.FailedAt)
+	assert.Nil(failed.SentAt)
+	assert.Nil(failed.Payload)
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ApiKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	MerchantID uuid.UUID  `gorm:"type:uuid;index" json:"merchantId"`
+	Name       string     `json:"name"`
+	Prefix     string     `gorm:"uniqueIndex" json:"prefix"`
+	Hash       string     `json:"-"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (k *ApiKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
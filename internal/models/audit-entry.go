@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+// AuditEntry records a security event. Subject is what the event is about,
+// such as an email or client IP, and ActorID the user who caused it, if any.
+type AuditEntry struct {
+	ID        uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	Action    enums.AuditAction `gorm:"index" json:"action"`
+	Subject   string            `gorm:"index" json:"subject"`
+	ActorID   string            `json:"actorId,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Detail    string            `json:"detail,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
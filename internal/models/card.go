@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Card is a tokenized card. The PAN and expiry only exist inside Ciphertext,
+// sealed with the vault key and bound to Token.
+type Card struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Token       string    `gorm:"uniqueIndex;not null" json:"token"`
+	Fingerprint string    `gorm:"index" json:"-"`
+	Ciphertext  []byte    `json:"-"`
+	Bin         string    `json:"bin"`
+	Last4       string    `json:"last4"`
+	UserID      string    `gorm:"index" json:"userId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package enums
+
+// AuditAction is the security event an audit entry records.
+type AuditAction string
+
+const (
+	AuditLoginLocked   = "login.locked"
+	AuditLoginUnlocked = "login.unlocked"
+
+	AuditTwoFactorEnabled  = "2fa.enabled"
+	AuditTwoFactorDisabled = "2fa.disabled"
+	AuditRecoveryCodeUsed  = "2fa.recovery_code_used"
+	AuditRolePolicyUpdated = "role_policy.updated"
+)
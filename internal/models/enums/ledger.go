@@ -0,0 +1,21 @@
+package enums
+
+// LedgerAccountType is the role of a ledger account. Customer and merchant
+// payable accounts belong to a user or merchant; platform revenue has no owner.
+type LedgerAccountType string
+
+const (
+	AccountCustomer        = "customer"
+	AccountMerchantPayable = "merchant_payable"
+	AccountPlatformRevenue = "platform_revenue"
+)
+
+// JournalKind is the business event a journal entry records.
+type JournalKind string
+
+const (
+	JournalPayment    = "payment"
+	JournalFee        = "fee"
+	JournalRefund     = "refund"
+	JournalChargeback = "chargeback"
+)
@@ -0,0 +1,12 @@
+package enums
+
+type PaymentEventSource string
+
+const (
+	EventSourceCreate  = "api.create"
+	EventSourceRefund  = "api.refund"
+	EventSourceBank    = "bank.update"
+	EventSourceCapture = "api.capture"
+	EventSourceVoid    = "api.void"
+	EventSourceExpiry  = "system.expiry"
+)
@@ -3,43 +3,146 @@ package enums
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 type PaymentStatus string
 
 const (
-	Unknown    = "Unknown"
-	Pending    = "Pending"
-	InProgress = "InProgress"
-	Approved   = "Approved"
-	Cancelled  = "Cancelled"
-	Failed     = "Failed"
+	Unknown           = "Unknown"
+	Pending           = "Pending"
+	InProgress        = "InProgress"
+	Approved          = "Approved"
+	Cancelled         = "Cancelled"
+	Failed            = "Failed"
+	RefundPending     = "RefundPending"
+	PartiallyRefunded = "PartiallyRefunded"
+	Refunded          = "Refunded"
+	Authorized        = "Authorized"
+	CapturePending    = "CapturePending"
+	VoidPending       = "VoidPending"
+	Voided            = "Voided"
+	Expired           = "Expired"
+	ChargedBack       = "ChargedBack"
 )
 
+var InvalidStatus = errors.New("invalid status value")
+
 var statusToString = map[PaymentStatus]string{
-	Unknown:    "Unknown",
-	Pending:    "Pending",
-	InProgress: "InProgress",
-	Approved:   "Approved",
-	Cancelled:  "Cancelled",
-	Failed:     "Failed",
+	Unknown:           "Unknown",
+	Pending:           "Pending",
+	InProgress:        "InProgress",
+	Approved:          "Approved",
+	Cancelled:         "Cancelled",
+	Failed:            "Failed",
+	RefundPending:     "RefundPending",
+	PartiallyRefunded: "PartiallyRefunded",
+	Refunded:          "Refunded",
+	Authorized:        "Authorized",
+	CapturePending:    "CapturePending",
+	VoidPending:       "VoidPending",
+	Voided:            "Voided",
+	Expired:           "Expired",
+	ChargedBack:       "ChargedBack",
 }
 
 var stringToStatus = map[string]PaymentStatus{
-	"Unknown":    Unknown,
-	"Pending":    Pending,
-	"InProgress": InProgress,
-	"Approved":   Approved,
-	"Cancelled":  Cancelled,
-	"Failed":     Failed,
+	"Unknown":           Unknown,
+	"Pending":           Pending,
+	"InProgress":        InProgress,
+	"Approved":          Approved,
+	"Cancelled":         Cancelled,
+	"Failed":            Failed,
+	"RefundPending":     RefundPending,
+	"PartiallyRefunded": PartiallyRefunded,
+	"Refunded":          Refunded,
+	"Authorized":        Authorized,
+	"CapturePending":    CapturePending,
+	"VoidPending":       VoidPending,
+	"Voided":            Voided,
+	"Expired":           Expired,
+	"ChargedBack":       ChargedBack,
+}
+
+// statusTransitions lists the statuses each status may move to. Statuses
+// without an entry (Failed, Cancelled, Refunded, Voided, Expired, ChargedBack)
+// are terminal, except RefundPending, which only refundTransitions leave.
+var statusTransitions = map[PaymentStatus][]PaymentStatus{
+	Pending:           {InProgress, Approved, Authorized, Failed},
+	InProgress:        {Approved, Authorized, Failed},
+	Authorized:        {CapturePending, VoidPending, Expired},
+	CapturePending:    {Approved, Authorized},
+	VoidPending:       {Voided, Authorized},
+	Approved:          {RefundPending, Cancelled, ChargedBack},
+	PartiallyRefunded: {RefundPending, ChargedBack},
+}
+
+// refundTransitions lists where the bank's answer to a refund may move a
+// payment. Only refund updates may take a payment out of RefundPending, so
+// a late or redelivered payment update cannot while a refund is pending.
+var refundTransitions = map[PaymentStatus][]PaymentStatus{
+	RefundPending: {Approved, PartiallyRefunded, Refunded},
+}
+
+// TransitionError is returned when a payment is asked to move between two
+// statuses the transition table does not connect.
+type TransitionError struct {
+	From PaymentStatus
+	To   PaymentStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal payment status transition from %s to %s", e.From, e.To)
 }
 
 func (s PaymentStatus) String() string {
 	return statusToString[s]
 }
 
-func Parse(string2 string) PaymentStatus {
-	return stringToStatus[string2]
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition returns a *TransitionError when s cannot move to next.
+func (s PaymentStatus) Transition(next PaymentStatus) error {
+	if !s.CanTransitionTo(next) {
+		return &TransitionError{From: s, To: next}
+	}
+	return nil
+}
+
+// Resolve returns the status a bank update reporting next means for a payment
+// in s. A failed capture leaves the authorization in place, so the payment
+// goes back to Authorized to be captured again or voided.
+func (s PaymentStatus) Resolve(next PaymentStatus) PaymentStatus {
+	if s == CapturePending && next == Failed {
+		return Authorized
+	}
+	return next
+}
+
+// RefundTransition returns a *TransitionError when a refund update cannot
+// move s to next.
+func (s PaymentStatus) RefundTransition(next PaymentStatus) error {
+	for _, allowed := range refundTransitions[s] {
+		if allowed == next {
+			return nil
+		}
+	}
+	return s.Transition(next)
+}
+
+func Parse(string2 string) (PaymentStatus, error) {
+	status, ok := stringToStatus[string2]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", InvalidStatus, string2)
+	}
+	return status, nil
 }
 
 func (s *PaymentStatus) UnmarshalJSON(data []byte) error {
@@ -50,7 +153,7 @@ func (s *PaymentStatus) UnmarshalJSON(data []byte) error {
 
 	status, ok := stringToStatus[statusStr]
 	if !ok {
-		return errors.New("invalid status value")
+		return InvalidStatus
 	}
 
 	*s = status
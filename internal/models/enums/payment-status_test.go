@@ -0,0 +1,99 @@
+package enums
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	status, err := Parse("Approved")
+
+	assert.Nil(err)
+	assert.Equal(PaymentStatus(Approved), status)
+}
+
+func TestParseInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	status, err := Parse("Aproved")
+
+	assert.True(errors.Is(err, InvalidStatus))
+	assert.Empty(status)
+}
+
+func TestTransition(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(PaymentStatus(Pending).Transition(InProgress))
+	assert.Nil(PaymentStatus(InProgress).Transition(Approved))
+	assert.Nil(PaymentStatus(Approved).Transition(RefundPending))
+}
+
+func TestRefundTransition(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(PaymentStatus(RefundPending).RefundTransition(Refunded))
+	assert.Nil(PaymentStatus(RefundPending).RefundTransition(PartiallyRefunded))
+	assert.Nil(PaymentStatus(RefundPending).RefundTransition(Approved))
+	assert.Nil(PaymentStatus(Approved).RefundTransition(RefundPending))
+	assert.NotNil(PaymentStatus(RefundPending).RefundTransition(ChargedBack))
+
+	assert.NotNil(PaymentStatus(RefundPending).Transition(Approved), "payment updates cannot leave RefundPending")
+	assert.NotNil(PaymentStatus(RefundPending).Transition(Refunded))
+	assert.NotNil(PaymentStatus(RefundPending).Transition(Cancelled))
+}
+
+func TestTransitionIllegal(t *testing.T) {
+	assert := assert.New(t)
+
+	err := PaymentStatus(Approved).Transition(Pending)
+
+	var transitionErr *TransitionError
+	assert.True(errors.As(err, &transitionErr))
+	assert.Equal(PaymentStatus(Approved), transitionErr.From)
+	assert.Equal(PaymentStatus(Pending), transitionErr.To)
+	assert.Equal("illegal payment status transition from Approved to Pending", err.Error())
+}
+
+func TestTransitionFromTerminal(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(PaymentStatus(Failed).CanTransitionTo(Approved))
+	assert.False(PaymentStatus(Refunded).CanTransitionTo(RefundPending))
+	assert.False(PaymentStatus(Cancelled).CanTransitionTo(Approved))
+}
+
+func TestTransitionAuthorization(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(PaymentStatus(Pending).Transition(Authorized))
+	assert.Nil(PaymentStatus(Authorized).Transition(CapturePending))
+	assert.Nil(PaymentStatus(CapturePending).Transition(Approved))
+	assert.Nil(PaymentStatus(CapturePending).Transition(Authorized))
+	assert.NotNil(PaymentStatus(CapturePending).Transition(Failed), "a failed capture keeps the authorization")
+	assert.Nil(PaymentStatus(Authorized).Transition(VoidPending))
+	assert.Nil(PaymentStatus(VoidPending).Transition(Voided))
+	assert.Nil(PaymentStatus(Authorized).Transition(Expired))
+	assert.NotNil(PaymentStatus(Authorized).Transition(RefundPending))
+	assert.NotNil(PaymentStatus(Expired).Transition(CapturePending))
+}
+
+func TestResolve(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(PaymentStatus(Authorized), PaymentStatus(CapturePending).Resolve(Failed))
+	assert.Equal(PaymentStatus(Approved), PaymentStatus(CapturePending).Resolve(Approved))
+	assert.Equal(PaymentStatus(Failed), PaymentStatus(Pending).Resolve(Failed))
+	assert.Nil(PaymentStatus(CapturePending).Transition(PaymentStatus(CapturePending).Resolve(Failed)))
+}
+
+func TestTransitionChargeback(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(PaymentStatus(Approved).Transition(ChargedBack))
+	assert.Nil(PaymentStatus(PartiallyRefunded).Transition(ChargedBack))
+	assert.NotNil(PaymentStatus(ChargedBack).Transition(RefundPending))
+}
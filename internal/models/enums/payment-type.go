@@ -7,19 +7,30 @@ import (
 
 type PaymentType string
 
+// Payment authorizes and captures in one step. Authorize only holds the
+// funds, which a later Capture collects (fully or in part) or Void releases.
 const (
-	Payment = "Payment"
-	Refund  = "Refund"
+	Payment   = "Payment"
+	Refund    = "Refund"
+	Authorize = "Authorize"
+	Capture   = "Capture"
+	Void      = "Void"
 )
 
 var typeToString = map[PaymentType]string{
-	Payment: "Payment",
-	Refund:  "Refund",
+	Payment:   "Payment",
+	Refund:    "Refund",
+	Authorize: "Authorize",
+	Capture:   "Capture",
+	Void:      "Void",
 }
 
 var stringToType = map[string]PaymentType{
-	"Payment": Payment,
-	"Refund":  Refund,
+	"Payment":   Payment,
+	"Refund":    Refund,
+	"Authorize": Authorize,
+	"Capture":   Capture,
+	"Void":      Void,
 }
 
 func (s PaymentType) String() string {
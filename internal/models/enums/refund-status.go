@@ -0,0 +1,9 @@
+package enums
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   = "Pending"
+	RefundStatusSucceeded = "Succeeded"
+	RefundStatusFailed    = "Failed"
+)
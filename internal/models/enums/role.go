@@ -0,0 +1,102 @@
+package enums
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+type Role string
+
+const (
+	RoleAdmin            = "admin"
+	RoleMerchantOperator = "merchant-operator"
+	RoleSupportReadOnly  = "support-readonly"
+	RoleCustomer         = "customer"
+)
+
+// Roles lists every role, most privileged first.
+var Roles = []Role{RoleAdmin, RoleMerchantOperator, RoleSupportReadOnly, RoleCustomer}
+
+var InvalidRole = errors.New("invalid role value")
+
+// Permission is an action a route may require.
+type Permission string
+
+const (
+	PermissionPaymentsRead    = "payments:read"
+	PermissionPaymentsCreate  = "payments:create"
+	PermissionPaymentsCapture = "payments:capture"
+	PermissionRefundsCreate   = "refunds:create"
+	PermissionCardsCreate     = "cards:create"
+	PermissionMerchantsRead   = "merchants:read"
+	PermissionMerchantsList   = "merchants:list"
+	PermissionMerchantsManage = "merchants:manage"
+	PermissionApiKeysManage   = "api-keys:manage"
+	PermissionBalancesRead    = "balances:read"
+	PermissionLedgerRead      = "ledger:read"
+	PermissionUsersManage     = "users:manage"
+	PermissionMetricsRead     = "metrics:read"
+)
+
+// rolePermissions lists what each role may do. Admins may do everything.
+// Which payments and merchants a role may touch is checked separately.
+var rolePermissions = map[Role][]Permission{
+	RoleMerchantOperator: {
+		PermissionPaymentsRead,
+		PermissionPaymentsCreate,
+		PermissionPaymentsCapture,
+		PermissionRefundsCreate,
+		PermissionMerchantsRead,
+		PermissionApiKeysManage,
+		PermissionBalancesRead,
+	},
+	RoleSupportReadOnly: {
+		PermissionPaymentsRead,
+		PermissionMerchantsRead,
+		PermissionMerchantsList,
+		PermissionBalancesRead,
+	},
+	RoleCustomer: {
+		PermissionPaymentsRead,
+		PermissionPaymentsCreate,
+		PermissionCardsCreate,
+	},
+}
+
+var stringToRole = map[string]Role{
+	"admin":             RoleAdmin,
+	"merchant-operator": RoleMerchantOperator,
+	"support-readonly":  RoleSupportReadOnly,
+	"customer":          RoleCustomer,
+}
+
+func (r Role) Can(permission Permission) bool {
+	if r == RoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func ParseRole(string2 string) (Role, error) {
+	role, ok := stringToRole[string2]
+	if !ok {
+		return "", InvalidRole
+	}
+	return role, nil
+}
+
+// UnmarshalJSON accepts known roles and leaves unknown ones empty, so a
+// token with a tampered or outdated role grants nothing.
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var roleStr string
+	if err := json.Unmarshal(data, &roleStr); err != nil {
+		return err
+	}
+	*r = stringToRole[roleStr]
+	return nil
+}
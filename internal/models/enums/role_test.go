@@ -0,0 +1,31 @@
+package enums
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRoleCan(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(Role(RoleAdmin).Can(PermissionLedgerRead))
+	assert.True(Role(RoleAdmin).Can(PermissionMetricsRead))
+	assert.False(Role(RoleSupportReadOnly).Can(PermissionMetricsRead))
+	assert.True(Role(RoleSupportReadOnly).Can(PermissionPaymentsRead))
+	assert.False(Role(RoleSupportReadOnly).Can(PermissionRefundsCreate))
+	assert.True(Role(RoleMerchantOperator).Can(PermissionRefundsCreate))
+	assert.False(Role(RoleCustomer).Can(PermissionPaymentsCapture))
+	assert.False(Role("").Can(PermissionPaymentsRead))
+}
+
+func TestRoleUnmarshalUnknown(t *testing.T) {
+	assert := assert.New(t)
+
+	var role Role
+	err := json.Unmarshal([]byte(`"superuser"`), &role)
+
+	assert.Nil(err)
+	assert.Empty(role)
+	assert.False(role.Can(PermissionPaymentsRead))
+}
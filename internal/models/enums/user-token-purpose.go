@@ -0,0 +1,10 @@
+package enums
+
+// UserTokenPurpose is what a token emailed to a user lets them do.
+type UserTokenPurpose string
+
+const (
+	UserTokenInvite            = "invite"
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+)
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+type IdempotencyKey struct {
+	Key         string    `gorm:"primaryKey" json:"key"`
+	Scope       string    `gorm:"primaryKey" json:"scope"`
+	RequestHash string    `json:"requestHash"`
+	StatusCode  int       `json:"statusCode"`
+	Response    []byte    `json:"response"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `gorm:"index" json:"expiresAt"`
+}
+
+// Completed reports whether the original request finished and its response was stored.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+var ErrImmutableLedger = errors.New("ledger records cannot be changed once written")
+
+// JournalEntry groups the postings of one business event. Reference is unique
+// so the same event is never booked twice.
+type JournalEntry struct {
+	ID        uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Reference string            `gorm:"uniqueIndex" json:"reference"`
+	Kind      enums.JournalKind `json:"kind"`
+	PaymentID uuid.UUID         `gorm:"type:uuid;index" json:"paymentId"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+func (JournalEntry) BeforeUpdate(*gorm.DB) error {
+	return ErrImmutableLedger
+}
+
+func (JournalEntry) BeforeDelete(*gorm.DB) error {
+	return ErrImmutableLedger
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+type LedgerAccount struct {
+	ID        uuid.UUID               `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Type      enums.LedgerAccountType `gorm:"uniqueIndex:idx_ledger_account" json:"type"`
+	OwnerID   string                  `gorm:"uniqueIndex:idx_ledger_account" json:"ownerId"`
+	Currency  string                  `gorm:"uniqueIndex:idx_ledger_account" json:"currency"`
+	CreatedAt time.Time               `json:"createdAt"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginThrottle counts consecutive failed logins for one key, an email or a
+// client IP, and holds the lockout that follows too many of them.
+type LoginThrottle struct {
+	Key           string `gorm:"primary_key"`
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+type Merchant struct {
+	ID            uuid.UUID     `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Name          string        `json:"name"`
+	Enabled       bool          `json:"enabled"`
+	Currencies    []string      `gorm:"serializer:json" json:"currencies"`
+	PaymentLimits []money.Money `gorm:"serializer:json" json:"paymentLimits"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+}
+
+func (m *Merchant) SupportsCurrency(currency string) bool {
+	for _, c := range m.Currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentLimit returns the largest single payment the merchant accepts in
+// currency, and false when there is no limit for it.
+func (m *Merchant) PaymentLimit(currency string) (money.Money, bool) {
+	for _, limit := range m.PaymentLimits {
+		if limit.Currency == currency {
+			return limit, true
+		}
+	}
+	return money.Money{}, false
+}
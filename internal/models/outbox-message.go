@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+func NewOutboxMessage(aggregateID uuid.UUID, message interface{}) (OutboxMessage, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	now := time.Now()
+	return OutboxMessage{
+		AggregateID:   aggregateID,
+		Payload:       payload,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+type OutboxMessage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	AggregateID   uuid.UUID  `gorm:"type:uuid;index" json:"aggregateId"`
+	Payload       []byte     `json:"payload"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError"`
+	NextAttemptAt time.Time  `gorm:"index" json:"nextAttemptAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	SentAt        *time.Time `gorm:"index" json:"sentAt"`
+	FailedAt      *time.Time `gorm:"index" json:"failedAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+type PaymentEvent struct {
+	ID             uuid.UUID                `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PaymentID      uuid.UUID                `gorm:"type:uuid;index" json:"paymentId"`
+	Source         enums.PaymentEventSource `json:"source"`
+	PreviousStatus enums.PaymentStatus      `json:"previousStatus"`
+	NewStatus      enums.PaymentStatus      `json:"newStatus"`
+	Msg            string                   `json:"msg"`
+	CreatedAt      time.Time                `gorm:"index" json:"createdAt"`
+}
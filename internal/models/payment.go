@@ -3,21 +3,26 @@ package models
 import (
 	"github.com/google/uuid"
 	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
 	"time"
 )
 
 type Payment struct {
-	ID            uuid.UUID           `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	CardID        string              `json:"cardId"`
-	TransactionID string              `json:"transactionId"`
-	RefundID      string              `json:"refundID"`
-	UserID        string              `json:"userId"`
-	MerchantID    string              `json:"merchantId"`
-	Amount        float64             `json:"amount"`
-	Status        enums.PaymentStatus `json:"status"`
-	Msg           string              `json:"msg"`
-	Currency      string              `json:"currency"`
-	Merchant      string              `json:"merchant"`
-	CreatedAt     time.Time           `json:"createdAt"`
-	UpdatedAt     time.Time           `json:"updatedAt"`
+	ID                     uuid.UUID           `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	CardToken              string              `gorm:"index" json:"-"`
+	CardBin                string              `json:"cardBin"`
+	CardLast4              string              `json:"cardLast4"`
+	TransactionID          string              `json:"transactionId"`
+	UserID                 string              `json:"userId"`
+	MerchantID             string              `json:"merchantId"`
+	Amount                 money.Money         `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	RefundedAmount         money.Money         `gorm:"embedded;embeddedPrefix:refunded_amount_" json:"refundedAmount"`
+	AuthorizedAmount       money.Money         `gorm:"embedded;embeddedPrefix:authorized_amount_" json:"authorizedAmount"`
+	CaptureAmount          money.Money         `gorm:"embedded;embeddedPrefix:capture_amount_" json:"captureAmount"`
+	AuthorizationExpiresAt *time.Time          `gorm:"index" json:"authorizationExpiresAt"`
+	Status                 enums.PaymentStatus `json:"status"`
+	Msg                    string              `json:"msg"`
+	Merchant               string              `json:"merchant"`
+	CreatedAt              time.Time           `json:"createdAt"`
+	UpdatedAt              time.Time           `json:"updatedAt"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+// Posting moves Amount into an account: debits are positive and credits
+// negative, so the postings of a journal entry sum to zero per currency.
+type Posting struct {
+	ID             uuid.UUID   `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	JournalEntryID uuid.UUID   `gorm:"type:uuid;index" json:"journalEntryId"`
+	AccountID      uuid.UUID   `gorm:"type:uuid;index" json:"accountId"`
+	Amount         money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	CreatedAt      time.Time   `json:"createdAt"`
+}
+
+func (Posting) BeforeUpdate(*gorm.DB) error {
+	return ErrImmutableLedger
+}
+
+func (Posting) BeforeDelete(*gorm.DB) error {
+	return ErrImmutableLedger
+}
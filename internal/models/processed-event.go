@@ -0,0 +1,14 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ProcessedEvent records a bank update applied to a payment, so the same
+// event redelivered by Kafka is not applied twice.
+type ProcessedEvent struct {
+	PaymentID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	EventID     string    `gorm:"primaryKey"`
+	ProcessedAt time.Time
+}
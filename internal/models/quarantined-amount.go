@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// QuarantinedAmount keeps a legacy float amount that could not be converted
+// to minor units exactly, because its currency is unknown or it has more
+// decimals than the currency allows. The converted row holds a best-effort
+// value; this record keeps the original for review.
+type QuarantinedAmount struct {
+	SourceTable string    `gorm:"primaryKey" json:"sourceTable"`
+	RowID       string    `gorm:"primaryKey" json:"rowId"`
+	Column      string    `gorm:"primaryKey" json:"column"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the
+// user has lost their authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	Hash      string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+type Refund struct {
+	ID           uuid.UUID          `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PaymentID    uuid.UUID          `gorm:"type:uuid;index" json:"paymentId"`
+	Amount       money.Money        `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Status       enums.RefundStatus `json:"status"`
+	BankRefundID string             `gorm:"index" json:"bankRefundId"`
+	Msg          string             `json:"msg"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	UpdatedAt    time.Time          `json:"updatedAt"`
+}
+
+func (r *Refund) IsPending() bool {
+	return r.Status == enums.RefundStatusPending
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+type RejectedTransition struct {
+	ID            uuid.UUID           `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PaymentID     uuid.UUID           `gorm:"type:uuid;index" json:"paymentId"`
+	FromStatus    enums.PaymentStatus `json:"fromStatus"`
+	ToStatus      enums.PaymentStatus `json:"toStatus"`
+	TransactionID string              `json:"transactionId"`
+	Msg           string              `json:"msg"`
+	CreatedAt     time.Time           `json:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+// RolePolicy holds the security settings admins set per role. Roles without
+// a row use the zero policy.
+type RolePolicy struct {
+	Role             enums.Role `gorm:"primary_key" json:"role"`
+	RequireTwoFactor bool       `json:"requireTwoFactor"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// RefreshToken is one link of a rotation chain. Each refresh uses up the
+// presented token and issues its successor in the same family; presenting a
+// used token again revokes the whole family.
+type RefreshToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	FamilyID  uuid.UUID `gorm:"type:uuid;index"`
+	Hash      string    `gorm:"uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// RevokedSession denies every access token of UserID issued up to
+// RevokedAt, as after a password change, until the last of them would have
+// expired anyway.
+type RevokedSession struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key"`
+	RevokedAt time.Time
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// RevokedToken denies the access token with ID JTI until it would have
+// expired anyway.
+type RevokedToken struct {
+	JTI       string    `gorm:"primary_key"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
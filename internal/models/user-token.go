@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
+	"time"
+)
+
+// UserToken is a single-use token emailed to a user, such as an invite or a
+// password reset link. Only its hash is stored.
+type UserToken struct {
+	ID        uuid.UUID              `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID    uuid.UUID              `gorm:"type:uuid;index"`
+	Purpose   enums.UserTokenPurpose `gorm:"index"`
+	Hash      string                 `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
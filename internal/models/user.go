@@ -2,40 +2,59 @@ package models
 
 import (
 	"github.com/google/uuid"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/pkg/auth"
 	"time"
 )
 
-func NewUser(firstName, lastName, email string) (*User, error) {
+// NewUser returns a user ready to be stored. An empty password leaves the
+// user unable to log in until they set one, as invited users do.
+func NewUser(firstName, lastName, email, password string, role enums.Role) (*User, error) {
 	u := &User{
 		FirstName: firstName,
 		LastName:  lastName,
 		Email:     email,
 		Enabled:   true,
+		Role:      role,
 		CreatedAt: time.Now(),
 	}
-	u.DefaultPassword()
+	if password == "" {
+		return u, nil
+	}
+	if err := u.SetPassword(password); err != nil {
+		return nil, err
+	}
 	return u, nil
 }
 
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	FirstName    string    `json:"firstName"`
-	LastName     string    `json:"lastName"`
-	Email        string    `json:"email" gorm:"unique;not null"`
-	Password     string    `json:"password,omitempty"`
-	PasswordSalt string    `json:"passwordSalt,omitempty"`
-	Enabled      bool      `json:"enabled"`
-	CreatedAt    time.Time `json:"createdAt"`
-}
-
-func (u *User) DefaultPassword() {
-	defaultPassword := "admin123"
-	u.SetPassword(defaultPassword)
+	ID              uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	FirstName       string     `json:"firstName"`
+	LastName        string     `json:"lastName"`
+	Email           string     `json:"email" gorm:"unique;not null"`
+	Password        string     `json:"password,omitempty"`
+	PasswordSalt    string     `json:"passwordSalt,omitempty"`
+	Enabled         bool       `json:"enabled"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	Role            enums.Role `gorm:"default:customer" json:"role"`
+	MerchantID      string     `json:"merchantId,omitempty"`
+	TOTPSecret      []byte     `json:"-"`
+	TOTPEnabled     bool       `json:"totpEnabled"`
+	TOTPLastStep    int64      `json:"-"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
 }
 
-func (u *User) SetPassword(password string) {
-	u.PasswordSalt, u.Password = auth.EncryptPassword(password)
+// SetPassword stores an argon2id hash of password. PasswordSalt is only used
+// by legacy SHA-512 hashes and is cleared.
+func (u *User) SetPassword(password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	u.PasswordSalt = ""
+	return nil
 }
 
 func (u *User) CleanSensitiveInfo() {
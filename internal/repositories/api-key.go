@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+	"time"
+)
+
+type ApiKeyRepository interface {
+	CreateApiKey(key models.ApiKey) (models.ApiKey, error)
+	GetApiKeyByPrefix(prefix string) (models.ApiKey, error)
+	GetApiKeysByMerchantID(merchantID uuid.UUID) ([]models.ApiKey, error)
+	UpdateApiKey(key models.ApiKey) (models.ApiKey, error)
+	TouchApiKey(id uuid.UUID, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewApiKeyRepository(db *gorm.DB) ApiKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) CreateApiKey(key models.ApiKey) (models.ApiKey, error) {
+	if err := r.db.Create(&key).Error; err != nil {
+		return key, err
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) GetApiKeyByPrefix(prefix string) (models.ApiKey, error) {
+	var key models.ApiKey
+	if err := r.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return key, err
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) GetApiKeysByMerchantID(merchantID uuid.UUID) ([]models.ApiKey, error) {
+	var keys []models.ApiKey
+	err := r.db.Where("merchant_id = ?", merchantID).Order("created_at").Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) UpdateApiKey(key models.ApiKey) (models.ApiKey, error) {
+	if err := r.db.Save(&key).Error; err != nil {
+		return key, err
+	}
+	return key, nil
+}
+
+// TouchApiKey records usedAt as the key's last use without touching other columns.
+func (r *apiKeyRepository) TouchApiKey(id uuid.UUID, usedAt time.Time) error {
+	return r.db.Model(&models.ApiKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
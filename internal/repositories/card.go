@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+)
+
+type CardRepository interface {
+	CreateCard(card models.Card) (models.Card, error)
+	GetCardByToken(token string) (models.Card, error)
+}
+
+type cardRepository struct {
+	db *gorm.DB
+}
+
+func NewCardRepository(db *gorm.DB) CardRepository {
+	return &cardRepository{db}
+}
+
+func (r *cardRepository) CreateCard(card models.Card) (models.Card, error) {
+	if err := r.db.Create(&card).Error; err != nil {
+		return card, err
+	}
+	return card, nil
+}
+
+func (r *cardRepository) GetCardByToken(token string) (models.Card, error) {
+	var card models.Card
+	if err := r.db.Where("token = ?", token).First(&card).Error; err != nil {
+		return card, err
+	}
+	return card, nil
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"payment-payments-api/internal/models"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	CreateIdempotencyKey(key models.IdempotencyKey) (bool, error)
+	GetIdempotencyKey(scope, key string) (models.IdempotencyKey, error)
+	UpdateIdempotencyKey(key models.IdempotencyKey, expiresAt time.Time) (bool, error)
+	DeleteIdempotencyKey(key models.IdempotencyKey) error
+	DeleteExpiredIdempotencyKeys(now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db}
+}
+
+// CreateIdempotencyKey inserts the key and reports false when it already exists.
+func (r *idempotencyRepository) CreateIdempotencyKey(key models.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *idempotencyRepository) GetIdempotencyKey(scope, key string) (models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.Where("scope = ? AND key = ?", scope, key).First(&record).Error; err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+// UpdateIdempotencyKey overwrites the stored key only while it still expires
+// at expiresAt, and reports false when another request got there first.
+func (r *idempotencyRepository) UpdateIdempotencyKey(key models.IdempotencyKey, expiresAt time.Time) (bool, error) {
+	result := r.db.Model(&key).Where("expires_at = ?", expiresAt).Select("*").Updates(&key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteIdempotencyKey deletes the key unless another request has taken it over.
+func (r *idempotencyRepository) DeleteIdempotencyKey(key models.IdempotencyKey) error {
+	return r.db.Where("scope = ? AND key = ? AND expires_at = ?", key.Scope, key.Key, key.ExpiresAt).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+func (r *idempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+)
+
+// UnbalancedJournal is a journal entry whose postings in Currency do not sum to zero.
+type UnbalancedJournal struct {
+	JournalEntryID uuid.UUID
+	Currency       string
+	Minor          int64
+}
+
+type LedgerRepository interface {
+	GetOrCreateAccount(account models.LedgerAccount) (models.LedgerAccount, error)
+	CreateJournalEntry(entry models.JournalEntry, postings []models.Posting) (bool, error)
+	GetAccountBalances(accountType enums.LedgerAccountType, ownerID string) ([]money.Money, error)
+	CountJournalEntries() (int64, error)
+	GetUnbalancedJournals() ([]UnbalancedJournal, error)
+}
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &ledgerRepository{db}
+}
+
+// GetOrCreateAccount returns the account of the given type, owner and
+// currency, opening it on first use.
+func (r *ledgerRepository) GetOrCreateAccount(account models.LedgerAccount) (models.LedgerAccount, error) {
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error
+	if err != nil {
+		return account, err
+	}
+	var existing models.LedgerAccount
+	err = r.db.Where("type = ? AND owner_id = ? AND currency = ?", account.Type, account.OwnerID, account.Currency).
+		First(&existing).Error
+	if err != nil {
+		return account, err
+	}
+	return existing, nil
+}
+
+// CreateJournalEntry books entry with its postings and reports false, writing
+// nothing, when an entry with the same reference already exists.
+func (r *ledgerRepository) CreateJournalEntry(entry models.JournalEntry, postings []models.Posting) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	for i := range postings {
+		postings[i].JournalEntryID = entry.ID
+		postings[i].CreatedAt = entry.CreatedAt
+	}
+	if err := r.db.Create(&postings).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetAccountBalances returns the sum of the postings of the owner's accounts
+// of accountType, one amount per currency.
+func (r *ledgerRepository) GetAccountBalances(accountType enums.LedgerAccountType, ownerID string) ([]money.Money, error) {
+	var rows []struct {
+		Currency string
+		Minor    int64
+	}
+	err := r.db.Model(&models.Posting{}).
+		Select("postings.amount_currency AS currency, COALESCE(SUM(postings.amount_minor), 0) AS minor").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.account_id").
+		Where("ledger_accounts.type = ? AND ledger_accounts.owner_id = ?", accountType, ownerID).
+		Group("postings.amount_currency").
+		Order("postings.amount_currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]money.Money, len(rows))
+	for i, row := range rows {
+		balances[i] = money.New(row.Minor, row.Currency)
+	}
+	return balances, nil
+}
+
+func (r *ledgerRepository) CountJournalEntries() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.JournalEntry{}).Count(&count).Error
+	return count, err
+}
+
+// GetUnbalancedJournals lists the journal entries whose postings do not sum
+// to zero in some currency, including entries without postings.
+func (r *ledgerRepository) GetUnbalancedJournals() ([]UnbalancedJournal, error) {
+	var journals []UnbalancedJournal
+	err := r.db.Raw(`SELECT journal_entries.id AS journal_entry_id,
+			COALESCE(postings.amount_currency, '') AS currency,
+			COALESCE(SUM(postings.amount_minor), 0) AS minor
+		FROM journal_entries
+		LEFT JOIN postings ON postings.journal_entry_id = journal_entries.id
+		GROUP BY journal_entries.id, postings.amount_currency
+		HAVING COALESCE(SUM(postings.amount_minor), 0) <> 0 OR COUNT(postings.id) = 0
+		ORDER BY journal_entries.id`).
+		Scan(&journals).Error
+	if err != nil {
+		return nil, err
+	}
+	return journals, nil
+}
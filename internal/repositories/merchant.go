@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/pkg/umdw"
+)
+
+type MerchantRepository interface {
+	CreateMerchant(merchant models.Merchant) (models.Merchant, error)
+	GetMerchantByID(id uuid.UUID) (models.Merchant, error)
+	UpdateMerchant(merchant models.Merchant) (models.Merchant, error)
+	ListMerchants(list umdw.List) ([]models.Merchant, int64, error)
+}
+
+type merchantRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantRepository(db *gorm.DB) MerchantRepository {
+	return &merchantRepository{db}
+}
+
+func (r *merchantRepository) CreateMerchant(merchant models.Merchant) (models.Merchant, error) {
+	if err := r.db.Create(&merchant).Error; err != nil {
+		return merchant, err
+	}
+	return merchant, nil
+}
+
+func (r *merchantRepository) GetMerchantByID(id uuid.UUID) (models.Merchant, error) {
+	var merchant models.Merchant
+	if err := r.db.First(&merchant, id).Error; err != nil {
+		return merchant, err
+	}
+	return merchant, nil
+}
+
+func (r *merchantRepository) UpdateMerchant(merchant models.Merchant) (models.Merchant, error) {
+	if err := r.db.Save(&merchant).Error; err != nil {
+		return merchant, err
+	}
+	return merchant, nil
+}
+
+// ListMerchants returns one page of merchants and the total count. list.By
+// must be a column name already checked by the caller.
+func (r *merchantRepository) ListMerchants(list umdw.List) ([]models.Merchant, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Merchant{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	desc := list.Sort == "desc"
+	var merchants []models.Merchant
+	err := r.db.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: list.By}, Desc: desc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc}).
+		Offset(list.Skip).
+		Limit(list.Limit).
+		Find(&merchants).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return merchants, total, nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"payment-payments-api/internal/models"
+	"time"
+)
+
+type OutboxRepository interface {
+	CreateOutboxMessage(message models.OutboxMessage) (models.OutboxMessage, error)
+	ClaimPendingOutboxMessages(now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error)
+	UpdateOutboxMessage(message models.OutboxMessage) (models.OutboxMessage, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db}
+}
+
+func (r *outboxRepository) CreateOutboxMessage(message models.OutboxMessage) (models.OutboxMessage, error) {
+	if err := r.db.Create(&message).Error; err != nil {
+		return message, err
+	}
+	return message, nil
+}
+
+// outboxClaimLock is the advisory lock key that serialises claims.
+const outboxClaimLock = 7405310
+
+// ClaimPendingOutboxMessages returns up to limit unsent messages that are due,
+// oldest first, and pushes their next attempt forward by lease, so concurrent
+// relays skip them. A message is only claimed while no older message of its
+// aggregate is waiting for a retry or held by another relay, so every
+// aggregate is published in order. Claims are serialised so that check sees
+// the leases of the claim before. Failed messages no longer hold back the
+// messages after them.
+func (r *outboxRepository) ClaimPendingOutboxMessages(now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", outboxClaimLock).Error; err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", now).
+			Where(`NOT EXISTS (SELECT 1 FROM outbox_messages earlier
+				WHERE earlier.aggregate_id = outbox_messages.aggregate_id
+				AND earlier.created_at < outbox_messages.created_at
+				AND earlier.sent_at IS NULL AND earlier.failed_at IS NULL
+				AND earlier.next_attempt_at > ?)`, now).
+			Order("created_at").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		return tx.Model(&models.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return messages, err
+}
+
+func (r *outboxRepository) UpdateOutboxMessage(message models.OutboxMessage) (models.OutboxMessage, error) {
+	if err := r.db.Save(&message).Error; err != nil {
+		return message, err
+	}
+	return message, nil
+}
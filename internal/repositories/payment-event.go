@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+)
+
+type PaymentEventRepository interface {
+	CreatePaymentEvent(event models.PaymentEvent) (models.PaymentEvent, error)
+	GetPaymentEventsByPaymentID(paymentID uuid.UUID) ([]models.PaymentEvent, error)
+}
+
+type paymentEventRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentEventRepository(db *gorm.DB) PaymentEventRepository {
+	return &paymentEventRepository{db}
+}
+
+func (r *paymentEventRepository) CreatePaymentEvent(event models.PaymentEvent) (models.PaymentEvent, error) {
+	if err := r.db.Create(&event).Error; err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+func (r *paymentEventRepository) GetPaymentEventsByPaymentID(paymentID uuid.UUID) ([]models.PaymentEvent, error) {
+	var events []models.PaymentEvent
+	err := r.db.Where("payment_id = ?", paymentID).
+		Order("created_at").
+		Find(&events).Error
+	if err != nil {
+		return events, err
+	}
+	return events, nil
+}
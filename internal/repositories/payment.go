@@ -3,14 +3,38 @@ package repositories
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/umdw"
+	"time"
 )
 
+// PaymentFilter narrows ListPayments. Zero values are ignored. MinAmount and
+// MaxAmount are minor units of Currency.
+type PaymentFilter struct {
+	UserID      string
+	MerchantID  string
+	Status      enums.PaymentStatus
+	Currency    string
+	MinAmount   *int64
+	MaxAmount   *int64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
 type PaymentRepository interface {
 	CreatePayment(payment models.Payment) (models.Payment, error)
 	GetPaymentByID(id uuid.UUID) (models.Payment, error)
 	GetPaymentByTransactionID(transactionID string) (models.Payment, error)
+	GetPaymentByIDForUpdate(id uuid.UUID) (models.Payment, error)
+	GetPaymentByTransactionIDForUpdate(transactionID string) (models.Payment, error)
 	UpdatePayment(payment models.Payment) (models.Payment, error)
+	CreateRejectedTransition(rejection models.RejectedTransition) error
+	CreateProcessedEvent(event models.ProcessedEvent) (bool, error)
+	ListPayments(filter PaymentFilter, list umdw.List) ([]models.Payment, int64, error)
+	ListPaymentsByCursor(filter PaymentFilter, cursor *umdw.Cursor, desc bool, limit int) ([]models.Payment, error)
+	ClaimExpiredAuthorizations(now time.Time, limit int) ([]models.Payment, error)
 }
 
 type paymentRepository struct {
@@ -44,9 +68,144 @@ func (r *paymentRepository) GetPaymentByTransactionID(transactionID string) (mod
 	return payment, nil
 }
 
+// GetPaymentByIDForUpdate locks the payment row until the surrounding transaction ends.
+func (r *paymentRepository) GetPaymentByIDForUpdate(id uuid.UUID) (models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, id).Error
+	if err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
+
+// GetPaymentByTransactionIDForUpdate locks the payment row until the surrounding transaction ends.
+func (r *paymentRepository) GetPaymentByTransactionIDForUpdate(transactionID string) (models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("transaction_id = ?", transactionID).
+		First(&payment).Error
+	if err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
+
 func (r *paymentRepository) UpdatePayment(payment models.Payment) (models.Payment, error) {
 	if err := r.db.Save(&payment).Error; err != nil {
 		return payment, err
 	}
 	return payment, nil
 }
+
+func (r *paymentRepository) CreateRejectedTransition(rejection models.RejectedTransition) error {
+	return r.db.Create(&rejection).Error
+}
+
+// CreateProcessedEvent records event and reports false when it was already
+// recorded.
+func (r *paymentRepository) CreateProcessedEvent(event models.ProcessedEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListPayments returns one page of the payments matching filter and the total
+// number of matches. list.By must be a column name already checked by the
+// caller; rows are ordered by it and then by id so pages are stable.
+func (r *paymentRepository) ListPayments(filter PaymentFilter, list umdw.List) ([]models.Payment, int64, error) {
+	query := filterPayments(r.db.Model(&models.Payment{}), filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	desc := list.Sort == "desc"
+	var payments []models.Payment
+	err := query.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: list.By}, Desc: desc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc}).
+		Offset(list.Skip).
+		Limit(list.Limit).
+		Find(&payments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return payments, total, nil
+}
+
+// ListPaymentsByCursor returns up to limit payments matching filter that come
+// after cursor in (created_at, id) order, or before it when cursor.Before is
+// set. Rows are returned in the order they were read, so a backward page comes
+// back reversed. A nil cursor starts from the beginning of the list.
+func (r *paymentRepository) ListPaymentsByCursor(filter PaymentFilter, cursor *umdw.Cursor, desc bool, limit int) ([]models.Payment, error) {
+	query := filterPayments(r.db.Model(&models.Payment{}), filter)
+
+	readDesc := desc
+	if cursor != nil {
+		if cursor.Before {
+			readDesc = !desc
+		}
+		operator := ">"
+		if readDesc {
+			operator = "<"
+		}
+		query = query.Where("(created_at, id) "+operator+" (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var payments []models.Payment
+	err := query.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: readDesc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: readDesc}).
+		Limit(limit).
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// ClaimExpiredAuthorizations locks up to limit authorized payments whose
+// authorization lapsed before now, skipping rows other transactions hold.
+func (r *paymentRepository) ClaimExpiredAuthorizations(now time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND authorization_expires_at < ?", enums.Authorized, now).
+		Order("authorization_expires_at").
+		Limit(limit).
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func filterPayments(query *gorm.DB, filter PaymentFilter) *gorm.DB {
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.MerchantID != "" {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Currency != "" {
+		query = query.Where("amount_currency = ?", filter.Currency)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount_minor >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount_minor <= ?", *filter.MaxAmount)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	return query
+}
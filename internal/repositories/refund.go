@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+)
+
+type RefundRepository interface {
+	CreateRefund(refund models.Refund) (models.Refund, error)
+	GetRefundByID(id uuid.UUID) (models.Refund, error)
+	GetRefundByBankRefundID(paymentID uuid.UUID, bankRefundID string) (models.Refund, error)
+	GetRefundsByPaymentID(paymentID uuid.UUID) ([]models.Refund, error)
+	UpdateRefund(refund models.Refund) (models.Refund, error)
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	return &refundRepository{db}
+}
+
+func (r *refundRepository) CreateRefund(refund models.Refund) (models.Refund, error) {
+	if err := r.db.Create(&refund).Error; err != nil {
+		return refund, err
+	}
+	return refund, nil
+}
+
+func (r *refundRepository) GetRefundByID(id uuid.UUID) (models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.First(&refund, id).Error; err != nil {
+		return refund, err
+	}
+	return refund, nil
+}
+
+func (r *refundRepository) GetRefundByBankRefundID(paymentID uuid.UUID, bankRefundID string) (models.Refund, error) {
+	var refund models.Refund
+	err := r.db.Where("payment_id = ? AND bank_refund_id = ?", paymentID, bankRefundID).
+		First(&refund).Error
+	if err != nil {
+		return refund, err
+	}
+	return refund, nil
+}
+
+func (r *refundRepository) GetRefundsByPaymentID(paymentID uuid.UUID) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.Where("payment_id = ?", paymentID).
+		Order("created_at").
+		Find(&refunds).Error
+	if err != nil {
+		return refunds, err
+	}
+	return refunds, nil
+}
+
+func (r *refundRepository) UpdateRefund(refund models.Refund) (models.Refund, error) {
+	if err := r.db.Save(&refund).Error; err != nil {
+		return refund, err
+	}
+	return refund, nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"payment-payments-api/internal/models"
+	"time"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error)
+	GetRefreshTokenByHash(hash string) (models.RefreshToken, error)
+	UseRefreshToken(id uuid.UUID, usedAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(familyID uuid.UUID, revokedAt time.Time) error
+	RevokeAccessToken(token models.RevokedToken) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	RevokeUserSessions(session models.RevokedSession) error
+	IsSessionRevoked(userID uuid.UUID, issuedAt time.Time) (bool, error)
+	DeleteExpiredTokens(now time.Time) (int64, error)
+}
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error) {
+	if err := r.db.Create(&token).Error; err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+func (r *tokenRepository) GetRefreshTokenByHash(hash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Where("hash = ?", hash).First(&token).Error; err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+// UseRefreshToken marks the token as used and reports whether this call did
+// it, so two concurrent refreshes with the same token cannot both succeed.
+func (r *tokenRepository) UseRefreshToken(id uuid.UUID, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", usedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *tokenRepository) RevokeRefreshTokenFamily(familyID uuid.UUID, revokedAt time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *tokenRepository) RevokeAccessToken(token models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error
+}
+
+func (r *tokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeUserSessions revokes every refresh token family of the session's
+// user and denies the access tokens issued to them until then. A later
+// revocation of the same user moves the cutoff forward.
+func (r *tokenRepository) RevokeUserSessions(session models.RevokedSession) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", session.UserID).
+			Update("revoked_at", session.RevokedAt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at"}),
+		}).Create(&session).Error
+	})
+}
+
+func (r *tokenRepository) IsSessionRevoked(userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RevokedSession{}).
+		Where("user_id = ? AND revoked_at >= ?", userID, issuedAt).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpiredTokens removes refresh tokens and denylist entries that
+// expired before now and returns how many rows were deleted.
+func (r *tokenRepository) DeleteExpiredTokens(now time.Time) (int64, error) {
+	refresh := r.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	if refresh.Error != nil {
+		return 0, refresh.Error
+	}
+	revoked := r.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{})
+	if revoked.Error != nil {
+		return 0, revoked.Error
+	}
+	sessions := r.db.Where("expires_at < ?", now).Delete(&models.RevokedSession{})
+	return refresh.RowsAffected + revoked.RowsAffected + sessions.RowsAffected, sessions.Error
+}
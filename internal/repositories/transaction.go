@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+)
+
+// TxRepositories groups the repositories bound to a single database transaction.
+type TxRepositories struct {
+	Payment      PaymentRepository
+	PaymentEvent PaymentEventRepository
+	Refund       RefundRepository
+	Outbox       OutboxRepository
+	Ledger       LedgerRepository
+}
+
+type Transactor interface {
+	Transaction(fn func(r TxRepositories) error) error
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db}
+}
+
+// Transaction runs fn with repositories sharing one transaction. It commits
+// when fn returns nil and rolls back otherwise.
+func (t *transactor) Transaction(fn func(r TxRepositories) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(TxRepositories{
+			Payment:      NewPaymentRepository(tx),
+			PaymentEvent: NewPaymentEventRepository(tx),
+			Refund:       NewRefundRepository(tx),
+			Outbox:       NewOutboxRepository(tx),
+			Ledger:       NewLedgerRepository(tx),
+		})
+	})
+}
@@ -3,14 +3,33 @@ package repositories
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	models "payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/umdw"
+	"time"
 )
 
 type UserRepository interface {
 	CreateUser(user *models.User) (*models.User, error)
-	UpdateUser(user *models.User) error
+	UpdateUser(user *models.User, fields ...string) error
 	FindByID(id uuid.UUID) (*models.User, error)
 	GetPaymentByEmail(email string) (*models.User, error)
+	ListUsers(list umdw.List) ([]models.User, int64, error)
+	GetLoginThrottle(key string) (*models.LoginThrottle, error)
+	IncrementLoginFailures(key string, at time.Time) (*models.LoginThrottle, error)
+	LockLogin(key string, until time.Time) error
+	DeleteLoginThrottle(key string) error
+	CreateAuditEntry(entry *models.AuditEntry) error
+	UseTOTPStep(userID uuid.UUID, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uuid.UUID, codes []models.RecoveryCode) error
+	UseRecoveryCode(userID uuid.UUID, hash string, usedAt time.Time) (bool, error)
+	DeleteRecoveryCodes(userID uuid.UUID) error
+	GetRolePolicy(role enums.Role) (*models.RolePolicy, error)
+	GetRolePolicies() ([]models.RolePolicy, error)
+	SaveRolePolicy(policy *models.RolePolicy) error
+	CreateUserToken(token *models.UserToken) error
+	UseUserToken(hash string, purpose enums.UserTokenPurpose, now time.Time) (*models.UserToken, error)
 }
 
 type userRepository struct {
@@ -36,8 +55,11 @@ func (r *userRepository) FindByID(id uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) UpdateUser(user *models.User) error {
-	return r.db.Save(user).Error
+// UpdateUser writes only the given fields of user, zero values included, so
+// columns updated concurrently, such as the TOTP step, are not overwritten
+// with a stale copy.
+func (r *userRepository) UpdateUser(user *models.User, fields ...string) error {
+	return r.db.Model(user).Select(fields).Updates(user).Error
 }
 
 func (r *userRepository) GetPaymentByEmail(email string) (*models.User, error) {
@@ -47,3 +69,146 @@ func (r *userRepository) GetPaymentByEmail(email string) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// ListUsers returns one page of users and the total count. list.By must be a
+// column name already checked by the caller.
+func (r *userRepository) ListUsers(list umdw.List) ([]models.User, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	desc := list.Sort == "desc"
+	var users []models.User
+	err := r.db.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: list.By}, Desc: desc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc}).
+		Offset(list.Skip).
+		Limit(list.Limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepository) GetLoginThrottle(key string) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	if err := r.db.Where("key = ?", key).First(&throttle).Error; err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// IncrementLoginFailures atomically adds a failure to key and returns the
+// updated counts.
+func (r *userRepository) IncrementLoginFailures(key string, at time.Time) (*models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Key: key, Failures: 1, LastFailureAt: at}
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("login_throttles.failures + 1"),
+				"last_failure_at": at,
+			}),
+		},
+		clause.Returning{},
+	).Create(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *userRepository) LockLogin(key string, until time.Time) error {
+	return r.db.Model(&models.LoginThrottle{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (r *userRepository) DeleteLoginThrottle(key string) error {
+	return r.db.Where("key = ?", key).Delete(&models.LoginThrottle{}).Error
+}
+
+func (r *userRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// UseTOTPStep records step as the user's last accepted TOTP step, reporting
+// false when an equal or later step was already used.
+func (r *userRepository) UseTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *userRepository) ReplaceRecoveryCodes(userID uuid.UUID, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks the user's unused code with hash as used and reports
+// whether there was one.
+func (r *userRepository) UseRecoveryCode(userID uuid.UUID, hash string, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) DeleteRecoveryCodes(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+func (r *userRepository) GetRolePolicy(role enums.Role) (*models.RolePolicy, error) {
+	var policy models.RolePolicy
+	if err := r.db.Where("role = ?", role).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *userRepository) GetRolePolicies() ([]models.RolePolicy, error) {
+	var policies []models.RolePolicy
+	if err := r.db.Order("role").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *userRepository) SaveRolePolicy(policy *models.RolePolicy) error {
+	return r.db.Save(policy).Error
+}
+
+// CreateUserToken stores token and voids the user's unused tokens for the
+// same purpose, so only the latest link works.
+func (r *userRepository) CreateUserToken(token *models.UserToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", token.CreatedAt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// UseUserToken marks the unexpired, unused token with hash as used and
+// returns it, or gorm.ErrRecordNotFound when there is none.
+func (r *userRepository) UseUserToken(hash string, purpose enums.UserTokenPurpose, now time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+	result := r.db.Model(&token).Clauses(clause.Returning{}).
+		Where("hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"net/url"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/auth"
+	"payment-payments-api/pkg/mail"
+	"time"
+)
+
+var (
+	InvalidUserToken     = errors.New("invalid or expired token")
+	EmailAlreadyVerified = errors.New("email already verified")
+)
+
+// AccountEmails configures the emails of the account flows. Links point to
+// pages under BaseURL that post the token back to the API.
+type AccountEmails struct {
+	BaseURL              string
+	InviteTTL            time.Duration
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+}
+
+type accountEmail struct {
+	subject string
+	path    string
+	text    string
+}
+
+var accountEmailTemplates = map[enums.UserTokenPurpose]accountEmail{
+	enums.UserTokenInvite: {
+		subject: "You have been invited to the Payments API",
+		path:    "/set-password",
+		text:    "An account was created for you. Choose your password with the link below",
+	},
+	enums.UserTokenPasswordReset: {
+		subject: "Reset your password",
+		path:    "/reset-password",
+		text:    "We received a request to reset your password. If it wasn't you, ignore this email. Reset it with the link below",
+	},
+	enums.UserTokenEmailVerification: {
+		subject: "Verify your email",
+		path:    "/verify-email",
+		text:    "Confirm this is your email address with the link below",
+	},
+}
+
+// AcceptInvite sets the first password of an invited user, which also
+// verifies their email.
+func (s *userService) AcceptInvite(token, password string) (*models.User, error) {
+	if err := checkPassword(password); err != nil {
+		return nil, err
+	}
+	user, err := s.useUserToken(token, enums.UserTokenInvite)
+	if err != nil {
+		return nil, err
+	}
+	if err = user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	s.markEmailVerified(user)
+	return user, s.repo.UpdateUser(user, "Password", "PasswordSalt", "EmailVerifiedAt", "UpdatedAt")
+}
+
+// ForgotPassword emails a reset link to email if it belongs to an enabled
+// user. It never reports whether it does.
+func (s *userService) ForgotPassword(email string) error {
+	user, err := s.GetUserByEmail(email)
+	if err != nil || !user.Enabled {
+		return nil
+	}
+	if err = s.sendAccountEmail(user, enums.UserTokenPasswordReset); err != nil {
+		log.Printf("Error sending password reset to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token, logs the user out of
+// every session and lifts any login lockout of the account.
+func (s *userService) ResetPassword(token, password string) error {
+	if err := checkPassword(password); err != nil {
+		return err
+	}
+	user, err := s.useUserToken(token, enums.UserTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	if err = user.SetPassword(password); err != nil {
+		return err
+	}
+	s.markEmailVerified(user)
+	if err = s.repo.UpdateUser(user, "Password", "PasswordSalt", "EmailVerifiedAt", "UpdatedAt"); err != nil {
+		return err
+	}
+	if err = s.tokenService.RevokeUserSessions(user.ID); err != nil {
+		return err
+	}
+	return s.repo.DeleteLoginThrottle(loginEmailKey(user.Email))
+}
+
+func (s *userService) VerifyEmail(token string) error {
+	user, err := s.useUserToken(token, enums.UserTokenEmailVerification)
+	if err != nil {
+		return err
+	}
+	s.markEmailVerified(user)
+	return s.repo.UpdateUser(user, "EmailVerifiedAt", "UpdatedAt")
+}
+
+// ResendVerification emails a new verification link, invalidating older ones.
+func (s *userService) ResendVerification(id uuid.UUID) error {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return EmailAlreadyVerified
+	}
+	return s.sendAccountEmail(user, enums.UserTokenEmailVerification)
+}
+
+// sendAccountEmail issues a token for purpose, replacing the user's unused
+// ones, and emails the link to it.
+func (s *userService) sendAccountEmail(user *models.User, purpose enums.UserTokenPurpose) error {
+	token, err := auth.NewEmailToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ttl := s.accountTokenTTL(purpose)
+	err = s.repo.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Hash:      auth.HashEmailToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	template := accountEmailTemplates[purpose]
+	link := s.accountEmails.BaseURL + template.path + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: template.subject,
+		Body: fmt.Sprintf("Hi %s,\n\n%s. It expires in %s.\n\n%s\n",
+			user.FirstName, template.text, ttl, link),
+	})
+}
+
+func (s *userService) accountTokenTTL(purpose enums.UserTokenPurpose) time.Duration {
+	switch purpose {
+	case enums.UserTokenInvite:
+		return s.accountEmails.InviteTTL
+	case enums.UserTokenPasswordReset:
+		return s.accountEmails.PasswordResetTTL
+	}
+	return s.accountEmails.EmailVerificationTTL
+}
+
+// useUserToken spends a token for purpose and returns its user.
+func (s *userService) useUserToken(token string, purpose enums.UserTokenPurpose) (*models.User, error) {
+	stored, err := s.repo.UseUserToken(auth.HashEmailToken(token), purpose, time.Now())
+	if err != nil {
+		return nil, InvalidUserToken
+	}
+	user, err := s.GetUserById(stored.UserID)
+	if err != nil {
+		return nil, InvalidUserToken
+	}
+	return user, nil
+}
+
+func (s *userService) markEmailVerified(user *models.User) {
+	now := time.Now()
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+	user.UpdatedAt = now
+}
+
+func checkPassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters", InvalidUser, minPasswordLength)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/auth"
+	"time"
+)
+
+var (
+	ApiKeyNotFound = errors.New("api key not found")
+	InvalidApiKey  = errors.New("invalid api key")
+)
+
+// apiKeyTouchInterval limits how often a key's last use is written back.
+const apiKeyTouchInterval = time.Minute
+
+type ApiKeyService interface {
+	CreateApiKey(merchantID uuid.UUID, dto dtoApi.ApiKeyRequest) (dtoApi.ApiKeyCreatedResponse, error)
+	ListApiKeys(merchantID uuid.UUID) ([]dtoApi.ApiKeyResponse, error)
+	RevokeApiKey(merchantID, id uuid.UUID) (models.ApiKey, error)
+	Authenticate(key string) (models.ApiKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepository repositories.ApiKeyRepository
+	merchantService  MerchantService
+}
+
+func NewApiKeyService(apiKeyRepository repositories.ApiKeyRepository,
+	merchantService MerchantService) *apiKeyService {
+	return &apiKeyService{apiKeyRepository: apiKeyRepository,
+		merchantService: merchantService,
+	}
+}
+
+// CreateApiKey issues a key for the merchant. The plain key is only part of
+// this response; the database keeps its prefix and hash.
+func (s *apiKeyService) CreateApiKey(merchantID uuid.UUID, request dtoApi.ApiKeyRequest) (dtoApi.ApiKeyCreatedResponse, error) {
+	if _, err := s.merchantService.GetMerchant(merchantID); err != nil {
+		return dtoApi.ApiKeyCreatedResponse{}, err
+	}
+	key, prefix, err := auth.NewApiKey()
+	if err != nil {
+		return dtoApi.ApiKeyCreatedResponse{}, err
+	}
+	model, err := s.apiKeyRepository.CreateApiKey(models.ApiKey{
+		MerchantID: merchantID,
+		Name:       request.Name,
+		Prefix:     prefix,
+		Hash:       auth.HashApiKey(key),
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return dtoApi.ApiKeyCreatedResponse{}, err
+	}
+	return dtoApi.ApiKeyCreatedResponse{
+		ApiKeyResponse: dtoApi.MapApiKeyToApiKeyResponse(&model),
+		Key:            key,
+	}, nil
+}
+
+func (s *apiKeyService) ListApiKeys(merchantID uuid.UUID) ([]dtoApi.ApiKeyResponse, error) {
+	keys, err := s.apiKeyRepository.GetApiKeysByMerchantID(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	dtos := make([]dtoApi.ApiKeyResponse, len(keys))
+	for i := range keys {
+		dtos[i] = dtoApi.MapApiKeyToApiKeyResponse(&keys[i])
+	}
+	return dtos, nil
+}
+
+func (s *apiKeyService) RevokeApiKey(merchantID, id uuid.UUID) (models.ApiKey, error) {
+	keys, err := s.apiKeyRepository.GetApiKeysByMerchantID(merchantID)
+	if err != nil {
+		return models.ApiKey{}, err
+	}
+	for _, key := range keys {
+		if key.ID != id {
+			continue
+		}
+		if !key.IsRevoked() {
+			now := time.Now()
+			key.RevokedAt = &now
+			return s.apiKeyRepository.UpdateApiKey(key)
+		}
+		return key, nil
+	}
+	return models.ApiKey{}, ApiKeyNotFound
+}
+
+// Authenticate returns the live key matching key. Keys that are unknown,
+// revoked or belong to a disabled merchant are rejected alike.
+func (s *apiKeyService) Authenticate(key string) (models.ApiKey, error) {
+	prefix, ok := auth.ApiKeyPrefix(key)
+	if !ok {
+		return models.ApiKey{}, InvalidApiKey
+	}
+	model, err := s.apiKeyRepository.GetApiKeyByPrefix(prefix)
+	if err != nil || model.IsRevoked() || !auth.VerifyApiKey(key, model.Hash) {
+		return models.ApiKey{}, InvalidApiKey
+	}
+	merchant, err := s.merchantService.GetMerchant(model.MerchantID)
+	if err != nil || !merchant.Enabled {
+		return models.ApiKey{}, InvalidApiKey
+	}
+
+	now := time.Now()
+	if model.LastUsedAt == nil || now.Sub(*model.LastUsedAt) >= apiKeyTouchInterval {
+		if err = s.apiKeyRepository.TouchApiKey(model.ID, now); err != nil {
+			return models.ApiKey{}, err
+		}
+		model.LastUsedAt = &now
+	}
+	return model, nil
+}
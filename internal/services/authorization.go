@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"log"
+	dtoApi "payment-payments-api/internal/api/dto"
+	dtoKafka "payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/repositories"
+	"time"
+)
+
+var (
+	CaptureCurrencyMismatch     = errors.New("capture currency does not match the authorized currency")
+	CaptureExceedsAuthorization = errors.New("capture amount exceeds the authorized amount")
+	AuthorizationExpired        = errors.New("authorization has expired")
+)
+
+const expireAuthorizationsBatchSize = 100
+
+// CapturePayment asks the bank to collect an authorized payment, for the
+// whole authorized amount or less. The requested amount is kept as the
+// capture amount and only becomes the payment amount, which later refunds
+// are checked against, once the bank approves the capture.
+func (s *paymentService) CapturePayment(id uuid.UUID, request dtoApi.CaptureRequest) (models.Payment, error) {
+	var payment models.Payment
+	err := s.transactor.Transaction(func(r repositories.TxRepositories) error {
+		var err error
+		payment, err = r.Payment.GetPaymentByIDForUpdate(id)
+		if err != nil {
+			return PaymentNotFound
+		}
+		if err = checkAuthorization(payment, enums.CapturePending); err != nil {
+			return err
+		}
+
+		amount := payment.AuthorizedAmount
+		if request.Amount != "" {
+			if amount, err = parseAmount(request.Amount, request.Currency); err != nil {
+				return err
+			}
+			if !amount.SameCurrency(payment.AuthorizedAmount) {
+				return CaptureCurrencyMismatch
+			}
+			if amount.Minor > payment.AuthorizedAmount.Minor {
+				return CaptureExceedsAuthorization
+			}
+		}
+
+		dto := dtoKafka.PaymentRequest{
+			PaymentID:     payment.ID.String(),
+			TransactionID: payment.TransactionID,
+			Type:          enums.Capture,
+			Status:        payment.Status,
+			Merchant:      payment.Merchant,
+		}
+		dto.SetAmount(amount)
+		payment.CaptureAmount = amount
+		payment, err = moveAuthorization(r, payment, enums.CapturePending, enums.EventSourceCapture)
+		if err != nil {
+			return err
+		}
+		return enqueuePaymentRequest(r.Outbox, payment, dto)
+	})
+	return payment, err
+}
+
+// VoidPayment asks the bank to release an authorization without capturing it.
+func (s *paymentService) VoidPayment(id uuid.UUID) (models.Payment, error) {
+	var payment models.Payment
+	err := s.transactor.Transaction(func(r repositories.TxRepositories) error {
+		var err error
+		payment, err = r.Payment.GetPaymentByIDForUpdate(id)
+		if err != nil {
+			return PaymentNotFound
+		}
+		if err = checkAuthorization(payment, enums.VoidPending); err != nil {
+			return err
+		}
+
+		dto := dtoKafka.PaymentRequest{
+			PaymentID:     payment.ID.String(),
+			TransactionID: payment.TransactionID,
+			Type:          enums.Void,
+			Status:        payment.Status,
+			Merchant:      payment.Merchant,
+		}
+		dto.SetAmount(payment.AuthorizedAmount)
+		payment, err = moveAuthorization(r, payment, enums.VoidPending, enums.EventSourceVoid)
+		if err != nil {
+			return err
+		}
+		return enqueuePaymentRequest(r.Outbox, payment, dto)
+	})
+	return payment, err
+}
+
+// ExpireAuthorizations moves authorizations left uncaptured past their
+// window to Expired. The bank is not notified: issuers drop the hold on
+// their own once the authorization lapses.
+func (s *paymentService) ExpireAuthorizations() (int64, error) {
+	var expired int64
+	for {
+		var claimed int
+		err := s.transactor.Transaction(func(r repositories.TxRepositories) error {
+			payments, err := r.Payment.ClaimExpiredAuthorizations(time.Now(), expireAuthorizationsBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(payments)
+			for _, payment := range payments {
+				if _, err = moveAuthorization(r, payment, enums.Expired, enums.EventSourceExpiry); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+		expired += int64(claimed)
+		if claimed < expireAuthorizationsBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// ExpireAuthorizationsEvery expires lapsed authorizations on every interval until ctx is done.
+func (s *paymentService) ExpireAuthorizationsEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireAuthorizations()
+			if err != nil {
+				log.Printf("Error expiring authorizations: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Expired %d uncaptured authorizations", expired)
+			}
+		}
+	}
+}
+
+// checkAuthorization reports whether payment is a live authorization that
+// may move to next.
+func checkAuthorization(payment models.Payment, next enums.PaymentStatus) error {
+	if err := payment.Status.Transition(next); err != nil {
+		return err
+	}
+	if payment.AuthorizationExpiresAt != nil && time.Now().After(*payment.AuthorizationExpiresAt) {
+		return AuthorizationExpired
+	}
+	return nil
+}
+
+func moveAuthorization(r repositories.TxRepositories, payment models.Payment,
+	next enums.PaymentStatus, source enums.PaymentEventSource) (models.Payment, error) {
+	previous := payment.Status
+	if err := previous.Transition(next); err != nil {
+		return payment, err
+	}
+	payment.Status = next
+	payment.UpdatedAt = time.Now()
+	payment, err := r.Payment.UpdatePayment(payment)
+	if err != nil {
+		return payment, err
+	}
+	return payment, recordPaymentEvent(r.PaymentEvent, payment, previous, source, "")
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/vault"
+	"time"
+)
+
+var (
+	CardNotFound = errors.New("card not found")
+	InvalidCard  = errors.New("invalid card")
+)
+
+type CardService interface {
+	TokenizeCard(request dtoApi.CardRequest) (models.Card, error)
+	GetCard(token, userID string) (models.Card, error)
+	SealForBank(card models.Card, cvc string, paymentID uuid.UUID) (string, error)
+}
+
+// vaultedCard is the plaintext sealed in models.Card.Ciphertext.
+type vaultedCard struct {
+	Number      string `json:"number"`
+	ExpiredDate string `json:"expiredDate"`
+}
+
+// bankCard is the plaintext sealed for the bank adapter. It is the only place
+// the CVC is ever written, and only for the outbound payment message.
+type bankCard struct {
+	Number      string `json:"number"`
+	ExpiredDate string `json:"expiredDate"`
+	CVC         string `json:"cvc"`
+}
+
+type cardService struct {
+	cardRepository repositories.CardRepository
+	vaultCipher    *vault.Cipher
+	bankCipher     *vault.Cipher
+	fingerprintKey []byte
+}
+
+func NewCardService(cardRepository repositories.CardRepository, vaultCipher, bankCipher *vault.Cipher,
+	fingerprintKey []byte) *cardService {
+	return &cardService{cardRepository: cardRepository,
+		vaultCipher:    vaultCipher,
+		bankCipher:     bankCipher,
+		fingerprintKey: fingerprintKey,
+	}
+}
+
+func (s *cardService) TokenizeCard(request dtoApi.CardRequest) (models.Card, error) {
+	pan := vault.NormalizePAN(request.Number)
+	if request.UserID == "" || !vault.ValidPAN(pan) || !vault.ValidExpiry(request.ExpiredDate, time.Now()) {
+		return models.Card{}, InvalidCard
+	}
+
+	token, err := vault.NewToken()
+	if err != nil {
+		return models.Card{}, err
+	}
+	plaintext, err := json.Marshal(vaultedCard{Number: pan, ExpiredDate: request.ExpiredDate})
+	if err != nil {
+		return models.Card{}, err
+	}
+	ciphertext, err := s.vaultCipher.Seal(plaintext, []byte(token))
+	if err != nil {
+		return models.Card{}, err
+	}
+
+	return s.cardRepository.CreateCard(models.Card{
+		Token:       token,
+		Fingerprint: vault.Fingerprint(s.fingerprintKey, pan),
+		Ciphertext:  ciphertext,
+		Bin:         vault.Bin(pan),
+		Last4:       vault.Last4(pan),
+		UserID:      request.UserID,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// GetCard returns the card behind token if it belongs to userID. Cards
+// without an owner, tokenized before cards were tied to users and not
+// claimed by the migration, cannot be used.
+func (s *cardService) GetCard(token, userID string) (models.Card, error) {
+	card, err := s.cardRepository.GetCardByToken(token)
+	if err != nil {
+		return card, CardNotFound
+	}
+	if card.UserID == "" || card.UserID != userID {
+		return models.Card{}, CardNotFound
+	}
+	return card, nil
+}
+
+// SealForBank decrypts card and re-encrypts it together with cvc under the key
+// shared with the bank adapter, using the payment ID as additional data. The
+// result is base64 encoded for the Kafka payload.
+func (s *cardService) SealForBank(card models.Card, cvc string, paymentID uuid.UUID) (string, error) {
+	plaintext, err := s.vaultCipher.Open(card.Ciphertext, []byte(card.Token))
+	if err != nil {
+		return "", err
+	}
+	var stored vaultedCard
+	if err = json.Unmarshal(plaintext, &stored); err != nil {
+		return "", err
+	}
+
+	plaintext, err = json.Marshal(bankCard{Number: stored.Number, ExpiredDate: stored.ExpiredDate, CVC: cvc})
+	if err != nil {
+		return "", err
+	}
+	sealed, err := s.bankCipher.Seal(plaintext, []byte(paymentID.String()))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
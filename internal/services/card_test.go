@@ -0,0 +1,60 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+	"testing"
+)
+
+type fakeCardRepository struct {
+	cards map[string]models.Card
+}
+
+func (r *fakeCardRepository) CreateCard(card models.Card) (models.Card, error) {
+	r.cards[card.Token] = card
+	return card, nil
+}
+
+func (r *fakeCardRepository) GetCardByToken(token string) (models.Card, error) {
+	card, ok := r.cards[token]
+	if !ok {
+		return card, gorm.ErrRecordNotFound
+	}
+	return card, nil
+}
+
+func TestGetCardRequiresOwner(t *testing.T) {
+	s := NewCardService(&fakeCardRepository{cards: map[string]models.Card{
+		"tok-owned":   {Token: "tok-owned", UserID: "user-1"},
+		"tok-unowned": {Token: "tok-unowned"},
+	}}, nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		token  string
+		userID string
+		err    error
+	}{
+		{"owner", "tok-owned", "user-1", nil},
+		{"another user", "tok-owned", "user-2", CardNotFound},
+		{"no user", "tok-owned", "", CardNotFound},
+		{"unowned card", "tok-unowned", "user-1", CardNotFound},
+		{"unowned card without user", "tok-unowned", "", CardNotFound},
+		{"unknown token", "tok-unknown", "user-1", CardNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			card, err := s.GetCard(test.token, test.userID)
+
+			assert.Equal(test.err, err)
+			if test.err == nil {
+				assert.Equal(test.token, card.Token)
+			} else {
+				assert.Empty(card.Token)
+			}
+		})
+	}
+}
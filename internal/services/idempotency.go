@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"time"
+)
+
+var (
+	IdempotencyKeyMismatch   = errors.New("idempotency key already used with a different request")
+	IdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	IdempotencyLeaseExpired  = errors.New("idempotency key was taken over before the request completed")
+)
+
+type IdempotencyService interface {
+	Begin(scope, key, requestHash string) (models.IdempotencyKey, bool, error)
+	Complete(record models.IdempotencyKey, statusCode int, response []byte) error
+	Release(record models.IdempotencyKey) error
+	PurgeExpired() (int64, error)
+}
+
+type idempotencyService struct {
+	idempotencyRepository repositories.IdempotencyRepository
+	retention             time.Duration
+	lease                 time.Duration
+}
+
+func NewIdempotencyService(idempotencyRepository repositories.IdempotencyRepository,
+	retention, lease time.Duration) *idempotencyService {
+	return &idempotencyService{idempotencyRepository: idempotencyRepository,
+		retention: retention,
+		lease:     lease,
+	}
+}
+
+// now is truncated to the microseconds Postgres keeps, since a reservation
+// is matched on its stored ExpiresAt.
+func (s *idempotencyService) now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// Begin reserves key within scope for a new request. The reservation holds
+// for the lease; after that, or once a completed key is past its retention,
+// the first request to take it over wins. When the key was already completed
+// with the same request hash, the stored record is returned with replay set
+// so the caller can send the cached response again.
+func (s *idempotencyService) Begin(scope, key, requestHash string) (models.IdempotencyKey, bool, error) {
+	now := s.now()
+	record := models.IdempotencyKey{
+		Key:         key,
+		Scope:       scope,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.lease),
+	}
+
+	created, err := s.idempotencyRepository.CreateIdempotencyKey(record)
+	if err != nil {
+		return record, false, err
+	}
+	if created {
+		return record, false, nil
+	}
+
+	existing, err := s.idempotencyRepository.GetIdempotencyKey(scope, key)
+	if err != nil {
+		return record, false, err
+	}
+	if existing.ExpiresAt.Before(now) {
+		taken, err := s.idempotencyRepository.UpdateIdempotencyKey(record, existing.ExpiresAt)
+		if err == nil && !taken {
+			err = IdempotencyKeyInProgress
+		}
+		return record, false, err
+	}
+	if existing.RequestHash != requestHash {
+		return existing, false, IdempotencyKeyMismatch
+	}
+	if !existing.Completed() {
+		return existing, false, IdempotencyKeyInProgress
+	}
+	return existing, true, nil
+}
+
+// Complete stores the response of the request that reserved record and keeps
+// it for the retention. Server errors are not cached so the client is free to
+// retry with the same key.
+func (s *idempotencyService) Complete(record models.IdempotencyKey, statusCode int, response []byte) error {
+	if statusCode >= 500 {
+		return s.Release(record)
+	}
+	completed := record
+	completed.StatusCode = statusCode
+	completed.Response = response
+	completed.ExpiresAt = s.now().Add(s.retention)
+	updated, err := s.idempotencyRepository.UpdateIdempotencyKey(completed, record.ExpiresAt)
+	if err == nil && !updated {
+		return IdempotencyLeaseExpired
+	}
+	return err
+}
+
+// Release frees the reservation of record unless another request has taken
+// the key over.
+func (s *idempotencyService) Release(record models.IdempotencyKey) error {
+	return s.idempotencyRepository.DeleteIdempotencyKey(record)
+}
+
+func (s *idempotencyService) PurgeExpired() (int64, error) {
+	return s.idempotencyRepository.DeleteExpiredIdempotencyKeys(time.Now())
+}
+
+// PurgeExpiredEvery deletes expired keys on every interval until ctx is done.
+func (s *idempotencyService) PurgeExpiredEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PurgeExpired()
+			if err != nil {
+				log.Printf("Error purging idempotency keys: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Purged %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+	"testing"
+	"time"
+)
+
+// fakeIdempotencyRepository keeps keys in memory. afterGet runs after every
+// read, letting a test race another request.
+type fakeIdempotencyRepository struct {
+	keys     map[string]models.IdempotencyKey
+	afterGet func()
+}
+
+func newFakeIdempotencyRepository() *fakeIdempotencyRepository {
+	return &fakeIdempotencyRepository{keys: map[string]models.IdempotencyKey{}}
+}
+
+func (r *fakeIdempotencyRepository) CreateIdempotencyKey(key models.IdempotencyKey) (bool, error) {
+	if _, ok := r.keys[key.Scope+"/"+key.Key]; ok {
+		return false, nil
+	}
+	r.keys[key.Scope+"/"+key.Key] = key
+	return true, nil
+}
+
+func (r *fakeIdempotencyRepository) GetIdempotencyKey(scope, key string) (models.IdempotencyKey, error) {
+	record, ok := r.keys[scope+"/"+key]
+	if r.afterGet != nil {
+		r.afterGet()
+	}
+	if !ok {
+		return record, gorm.ErrRecordNotFound
+	}
+	return record, nil
+}
+
+func (r *fakeIdempotencyRepository) UpdateIdempotencyKey(key models.IdempotencyKey, expiresAt time.Time) (bool, error) {
+	stored, ok := r.keys[key.Scope+"/"+key.Key]
+	if !ok || !stored.ExpiresAt.Equal(expiresAt) {
+		return false, nil
+	}
+	r.keys[key.Scope+"/"+key.Key] = key
+	return true, nil
+}
+
+func (r *fakeIdempotencyRepository) DeleteIdempotencyKey(key models.IdempotencyKey) error {
+	if stored, ok := r.keys[key.Scope+"/"+key.Key]; ok && stored.ExpiresAt.Equal(key.ExpiresAt) {
+		delete(r.keys, key.Scope+"/"+key.Key)
+	}
+	return nil
+}
+
+func (r *fakeIdempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	var deleted int64
+	for id, key := range r.keys {
+		if key.ExpiresAt.Before(now) {
+			delete(r.keys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func TestIdempotencyBeginReplaysCompletedRequest(t *testing.T) {
+	assert := assert.New(t)
+	s := NewIdempotencyService(newFakeIdempotencyRepository(), time.Hour, time.Minute)
+
+	record, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+	assert.Nil(err)
+	assert.False(replay)
+	assert.Nil(s.Complete(record, 201, []byte(`{"id":"p-1"}`)))
+
+	replayed, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+
+	assert.Nil(err)
+	assert.True(replay)
+	assert.Equal(201, replayed.StatusCode)
+	assert.Equal(`{"id":"p-1"}`, string(replayed.Response))
+}
+
+func TestIdempotencyBeginSeparatesScopes(t *testing.T) {
+	assert := assert.New(t)
+	s := NewIdempotencyService(newFakeIdempotencyRepository(), time.Hour, time.Minute)
+	record, _, _ := s.Begin("payments.void:user:1", "key-1", "hash")
+	_ = s.Complete(record, 200, []byte(`{}`))
+
+	_, replay, err := s.Begin("payments.void:user:2", "key-1", "hash")
+
+	assert.Nil(err)
+	assert.False(replay, "another caller's key is never replayed")
+}
+
+func TestIdempotencyBeginHashMismatch(t *testing.T) {
+	assert := assert.New(t)
+	s := NewIdempotencyService(newFakeIdempotencyRepository(), time.Hour, time.Minute)
+	record, _, _ := s.Begin("payments.create:user:1", "key-1", "hash")
+	_ = s.Complete(record, 201, []byte(`{}`))
+
+	_, replay, err := s.Begin("payments.create:user:1", "key-1", "other-hash")
+
+	assert.ErrorIs(err, IdempotencyKeyMismatch)
+	assert.False(replay)
+}
+
+func TestIdempotencyBeginInProgress(t *testing.T) {
+	assert := assert.New(t)
+	s := NewIdempotencyService(newFakeIdempotencyRepository(), time.Hour, time.Minute)
+	_, _, _ = s.Begin("payments.create:user:1", "key-1", "hash")
+
+	_, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+
+	assert.ErrorIs(err, IdempotencyKeyInProgress)
+	assert.False(replay)
+}
+
+func TestIdempotencyBeginExpiredKey(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeIdempotencyRepository()
+	s := NewIdempotencyService(repo, time.Hour, time.Minute)
+	repo.keys["payments.create:user:1/key-1"] = models.IdempotencyKey{
+		Key:         "key-1",
+		Scope:       "payments.create:user:1",
+		RequestHash: "old-hash",
+		StatusCode:  201,
+		Response:    []byte(`{}`),
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+
+	record, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+
+	assert.Nil(err)
+	assert.False(replay)
+	assert.Equal("hash", record.RequestHash)
+	assert.False(record.Completed())
+	assert.True(record.ExpiresAt.After(time.Now()))
+}
+
+func TestIdempotencyCompleteReleasesServerErrors(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeIdempotencyRepository()
+	s := NewIdempotencyService(repo, time.Hour, time.Minute)
+	record, _, _ := s.Begin("payments.create:user:1", "key-1", "hash")
+
+	assert.Nil(s.Complete(record, 503, []byte(`{"error":"unavailable"}`)))
+	assert.Empty(repo.keys)
+
+	_, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+	assert.Nil(err)
+	assert.False(replay, "the client may retry after a server error")
+}
+
+func TestIdempotencyBeginLeasesInProgressKeys(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeIdempotencyRepository()
+	s := NewIdempotencyService(repo, time.Hour, time.Minute)
+
+	record, _, err := s.Begin("payments.create:user:1", "key-1", "hash")
+	assert.Nil(err)
+	assert.WithinDuration(time.Now().Add(time.Minute), record.ExpiresAt, time.Second)
+
+	assert.Nil(s.Complete(record, 201, []byte(`{}`)))
+	completed := repo.keys["payments.create:user:1/key-1"]
+	assert.WithinDuration(time.Now().Add(time.Hour), completed.ExpiresAt, time.Second)
+}
+
+func TestIdempotencyBeginTakesOverExpiredLease(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeIdempotencyRepository()
+	s := NewIdempotencyService(repo, time.Hour, time.Minute)
+	stale, _, _ := s.Begin("payments.create:user:1", "key-1", "hash")
+	stale.ExpiresAt = time.Now().Add(-time.Second)
+	repo.keys["payments.create:user:1/key-1"] = stale
+
+	record, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+	assert.Nil(err)
+	assert.False(replay)
+
+	assert.ErrorIs(s.Complete(stale, 201, []byte(`{"stale":true}`)), IdempotencyLeaseExpired)
+	assert.Nil(s.Release(stale))
+	assert.Equal(record, repo.keys["payments.create:user:1/key-1"], "the stale request leaves the new reservation alone")
+}
+
+func TestIdempotencyBeginLosesTakeoverRace(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeIdempotencyRepository()
+	s := NewIdempotencyService(repo, time.Hour, time.Minute)
+	repo.keys["payments.create:user:1/key-1"] = models.IdempotencyKey{
+		Key:         "key-1",
+		Scope:       "payments.create:user:1",
+		RequestHash: "hash",
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+	repo.afterGet = func() {
+		repo.afterGet = nil
+		_, _, err := s.Begin("payments.create:user:1", "key-1", "hash")
+		assert.Nil(err)
+	}
+
+	_, replay, err := s.Begin("payments.create:user:1", "key-1", "hash")
+
+	assert.ErrorIs(err, IdempotencyKeyInProgress)
+	assert.False(replay)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+var LedgerUnbalanced = errors.New("journal entry postings do not balance")
+
+// LedgerService books payments, fees, refunds and chargebacks as double-entry
+// journals. The Record methods take the ledger repository of the caller's
+// transaction so postings commit together with the payment change.
+type LedgerService interface {
+	RecordPayment(ledger repositories.LedgerRepository, payment models.Payment) error
+	RecordRefund(ledger repositories.LedgerRepository, payment models.Payment, refund models.Refund) error
+	RecordChargeback(ledger repositories.LedgerRepository, payment models.Payment, amount money.Money) error
+	GetMerchantBalance(merchantID string) (dtoApi.MerchantBalanceResponse, error)
+	CheckIntegrity() (dtoApi.LedgerIntegrityResponse, error)
+}
+
+type ledgerService struct {
+	ledgerRepository repositories.LedgerRepository
+	platformFeeBps   int64
+}
+
+func NewLedgerService(ledgerRepository repositories.LedgerRepository, platformFeeBps int64) *ledgerService {
+	return &ledgerService{ledgerRepository: ledgerRepository,
+		platformFeeBps: platformFeeBps,
+	}
+}
+
+// ledgerLeg is one side of a journal entry before its account is resolved.
+type ledgerLeg struct {
+	accountType enums.LedgerAccountType
+	ownerID     string
+	amount      money.Money
+}
+
+// RecordPayment books a captured payment: the customer is debited and the
+// merchant payable credited, then the platform fee moves from the merchant
+// payable to platform revenue.
+func (s *ledgerService) RecordPayment(ledger repositories.LedgerRepository, payment models.Payment) error {
+	err := s.post(ledger, "payment:"+payment.ID.String(), enums.JournalPayment, payment.ID,
+		ledgerLeg{enums.AccountCustomer, payment.UserID, payment.Amount},
+		ledgerLeg{enums.AccountMerchantPayable, payment.MerchantID, negate(payment.Amount)},
+	)
+	if err != nil {
+		return err
+	}
+
+	fee := payment.Amount.BasisPoints(s.platformFeeBps)
+	if !fee.IsPositive() {
+		return nil
+	}
+	return s.post(ledger, "fee:"+payment.ID.String(), enums.JournalFee, payment.ID,
+		ledgerLeg{enums.AccountMerchantPayable, payment.MerchantID, fee},
+		ledgerLeg{enums.AccountPlatformRevenue, "", negate(fee)},
+	)
+}
+
+// RecordRefund books a settled refund back from the merchant payable to the customer.
+func (s *ledgerService) RecordRefund(ledger repositories.LedgerRepository, payment models.Payment, refund models.Refund) error {
+	return s.post(ledger, "refund:"+refund.ID.String(), enums.JournalRefund, payment.ID,
+		ledgerLeg{enums.AccountMerchantPayable, payment.MerchantID, refund.Amount},
+		ledgerLeg{enums.AccountCustomer, payment.UserID, negate(refund.Amount)},
+	)
+}
+
+// RecordChargeback books the amount the issuer took back from the merchant.
+func (s *ledgerService) RecordChargeback(ledger repositories.LedgerRepository, payment models.Payment, amount money.Money) error {
+	return s.post(ledger, "chargeback:"+payment.ID.String(), enums.JournalChargeback, payment.ID,
+		ledgerLeg{enums.AccountMerchantPayable, payment.MerchantID, amount},
+		ledgerLeg{enums.AccountCustomer, payment.UserID, negate(amount)},
+	)
+}
+
+// GetMerchantBalance returns what the platform owes the merchant per
+// currency, i.e. the credit balance of its payable accounts.
+func (s *ledgerService) GetMerchantBalance(merchantID string) (dtoApi.MerchantBalanceResponse, error) {
+	balances, err := s.ledgerRepository.GetAccountBalances(enums.AccountMerchantPayable, merchantID)
+	if err != nil {
+		return dtoApi.MerchantBalanceResponse{}, err
+	}
+	for i := range balances {
+		balances[i] = negate(balances[i])
+	}
+	return dtoApi.MerchantBalanceResponse{MerchantID: merchantID, Balances: balances}, nil
+}
+
+// CheckIntegrity verifies that every journal entry balances in every currency.
+func (s *ledgerService) CheckIntegrity() (dtoApi.LedgerIntegrityResponse, error) {
+	count, err := s.ledgerRepository.CountJournalEntries()
+	if err != nil {
+		return dtoApi.LedgerIntegrityResponse{}, err
+	}
+	journals, err := s.ledgerRepository.GetUnbalancedJournals()
+	if err != nil {
+		return dtoApi.LedgerIntegrityResponse{}, err
+	}
+	unbalanced := make([]dtoApi.UnbalancedJournalResponse, len(journals))
+	for i, journal := range journals {
+		unbalanced[i] = dtoApi.UnbalancedJournalResponse{
+			JournalEntryID: journal.JournalEntryID,
+			Difference:     money.New(journal.Minor, journal.Currency),
+		}
+	}
+	return dtoApi.LedgerIntegrityResponse{
+		Balanced:   len(unbalanced) == 0,
+		Journals:   count,
+		Unbalanced: unbalanced,
+	}, nil
+}
+
+// post books legs as one journal entry. Entries already booked under
+// reference are left untouched, so redelivered bank updates are harmless.
+func (s *ledgerService) post(ledger repositories.LedgerRepository, reference string, kind enums.JournalKind,
+	paymentID uuid.UUID, legs ...ledgerLeg) error {
+	totals := map[string]int64{}
+	for _, leg := range legs {
+		totals[leg.amount.Currency] += leg.amount.Minor
+	}
+	for _, total := range totals {
+		if total != 0 {
+			return LedgerUnbalanced
+		}
+	}
+
+	now := time.Now()
+	postings := make([]models.Posting, len(legs))
+	for i, leg := range legs {
+		account, err := ledger.GetOrCreateAccount(models.LedgerAccount{
+			Type:      leg.accountType,
+			OwnerID:   leg.ownerID,
+			Currency:  leg.amount.Currency,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		postings[i] = models.Posting{AccountID: account.ID, Amount: leg.amount}
+	}
+
+	_, err := ledger.CreateJournalEntry(models.JournalEntry{
+		Reference: reference,
+		Kind:      kind,
+		PaymentID: paymentID,
+		CreatedAt: now,
+	}, postings)
+	return err
+}
+
+func negate(m money.Money) money.Money {
+	return money.New(-m.Minor, m.Currency)
+}
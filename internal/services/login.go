@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/auth"
+	"strings"
+	"time"
+)
+
+var LoginThrottled = errors.New("too many failed login attempts")
+
+// dummyPasswordHash is verified against when the email is unknown, so such a
+// login takes as long as one with a wrong password. It uses the parameters
+// HashPassword currently hashes with.
+const dummyPasswordHash = "$argon2id$v=19$m=65536,t=3,p=2$+T4S89BCXXlHda8GTx3IEA$Gdvz3la+8w7wQzUwvo2FJu3IcMd2kBJofRPt+ykvPCc"
+
+// LoginThrottledError rejects a login attempt made before RetryAfter has
+// passed, either because of the delay after the last failure or a lockout.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", LoginThrottled, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == LoginThrottled
+}
+
+// LoginPolicy limits failed logins. Each failure delays the next attempt
+// for the same email or IP, doubling from BaseDelay up to MaxDelay, and
+// reaching MaxFailures for an email or MaxIPFailures for an IP locks it out
+// for LockoutDuration. Failures older than FailureWindow are forgotten.
+type LoginPolicy struct {
+	MaxFailures     int
+	MaxIPFailures   int
+	LockoutDuration time.Duration
+	FailureWindow   time.Duration
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+}
+
+// Login checks email and password for a client at ip. Failures are counted
+// per email and per IP whether or not the email exists, and a password is
+// always verified, so neither the responses nor their timing reveal which
+// accounts do.
+func (s *userService) Login(email, password, ip string) (*models.User, error) {
+	now := time.Now()
+	emailKey := loginEmailKey(email)
+	ipKey := loginIPKey(ip)
+	for _, key := range []string{emailKey, ipKey} {
+		if err := s.checkLoginThrottle(key, now); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.GetUserByEmail(email)
+	salt, hash := "", dummyPasswordHash
+	if err == nil {
+		salt, hash = user.PasswordSalt, user.Password
+	}
+	if !auth.VerifyPassword(password, salt, hash) || err != nil || !user.Enabled {
+		s.recordLoginFailure(emailKey, s.loginPolicy.MaxFailures, ip, now)
+		s.recordLoginFailure(ipKey, s.loginPolicy.MaxIPFailures, ip, now)
+		return nil, InvalidCredentials
+	}
+
+	if err = s.repo.DeleteLoginThrottle(emailKey); err != nil {
+		log.Printf("Error clearing login failures of %s: %v", emailKey, err)
+	}
+	if err = s.RehashPassword(user, password); err != nil {
+		log.Printf("Failed to rehash password of user %s: %v", user.ID, err)
+	}
+	return user, nil
+}
+
+// UnlockUser clears the failed logins and lockout of the user's email.
+func (s *userService) UnlockUser(id uuid.UUID, actorID string) error {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	key := loginEmailKey(user.Email)
+	if err = s.repo.DeleteLoginThrottle(key); err != nil {
+		return err
+	}
+	s.audit(enums.AuditLoginUnlocked, key, actorID, "", "")
+	return nil
+}
+
+// checkLoginThrottle returns a LoginThrottledError while key is locked out or
+// still inside the delay after its last failure. Stale state is cleared.
+func (s *userService) checkLoginThrottle(key string, now time.Time) error {
+	throttle, err := s.repo.GetLoginThrottle(key)
+	if err != nil {
+		return nil
+	}
+	if throttle.IsLocked(now) {
+		return &LoginThrottledError{RetryAfter: throttle.LockedUntil.Sub(now)}
+	}
+	if throttle.LockedUntil != nil || now.Sub(throttle.LastFailureAt) > s.loginPolicy.FailureWindow {
+		if err = s.repo.DeleteLoginThrottle(key); err != nil {
+			log.Printf("Error clearing login failures of %s: %v", key, err)
+		}
+		return nil
+	}
+	if retryAt := throttle.LastFailureAt.Add(s.loginDelay(throttle.Failures)); now.Before(retryAt) {
+		return &LoginThrottledError{RetryAfter: retryAt.Sub(now)}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failure for key and locks it out once it
+// reaches maxFailures. Errors are only logged so the caller still answers
+// with invalid credentials.
+func (s *userService) recordLoginFailure(key string, maxFailures int, ip string, now time.Time) {
+	throttle, err := s.repo.IncrementLoginFailures(key, now)
+	if err != nil {
+		log.Printf("Error recording login failure of %s: %v", key, err)
+		return
+	}
+	if throttle.Failures < maxFailures {
+		return
+	}
+	if err = s.repo.LockLogin(key, now.Add(s.loginPolicy.LockoutDuration)); err != nil {
+		log.Printf("Error locking out %s: %v", key, err)
+		return
+	}
+	s.audit(enums.AuditLoginLocked, key, "", ip,
+		fmt.Sprintf("%d failed logins, locked for %s", throttle.Failures, s.loginPolicy.LockoutDuration))
+}
+
+// loginDelay is how long to wait after the last of failures before trying
+// again.
+func (s *userService) loginDelay(failures int) time.Duration {
+	if failures < 1 || s.loginPolicy.BaseDelay <= 0 {
+		return 0
+	}
+	delay := s.loginPolicy.BaseDelay
+	for i := 1; i < failures && delay < s.loginPolicy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.loginPolicy.MaxDelay)
+}
+
+func (s *userService) audit(action enums.AuditAction, subject, actorID, ip, detail string) {
+	entry := &models.AuditEntry{
+		Action:    action,
+		Subject:   subject,
+		ActorID:   actorID,
+		IP:        ip,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateAuditEntry(entry); err != nil {
+		log.Printf("Error writing audit entry %s for %s: %v", action, subject, err)
+	}
+}
+
+func loginEmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
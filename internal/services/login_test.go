@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/auth"
+	"testing"
+	"time"
+)
+
+// fakeLoginRepository keeps login throttles in memory. Methods Login does
+// not use are left to the embedded nil interface.
+type fakeLoginRepository struct {
+	repositories.UserRepository
+	users     map[string]*models.User
+	throttles map[string]*models.LoginThrottle
+	audit     []*models.AuditEntry
+}
+
+func newFakeLoginRepository() *fakeLoginRepository {
+	return &fakeLoginRepository{users: map[string]*models.User{}, throttles: map[string]*models.LoginThrottle{}}
+}
+
+func (r *fakeLoginRepository) GetPaymentByEmail(email string) (*models.User, error) {
+	user, ok := r.users[email]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeLoginRepository) GetLoginThrottle(key string) (*models.LoginThrottle, error) {
+	throttle, ok := r.throttles[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return throttle, nil
+}
+
+func (r *fakeLoginRepository) IncrementLoginFailures(key string, at time.Time) (*models.LoginThrottle, error) {
+	throttle, ok := r.throttles[key]
+	if !ok {
+		throttle = &models.LoginThrottle{Key: key}
+		r.throttles[key] = throttle
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = at
+	copied := *throttle
+	return &copied, nil
+}
+
+func (r *fakeLoginRepository) LockLogin(key string, until time.Time) error {
+	r.throttles[key].LockedUntil = &until
+	return nil
+}
+
+func (r *fakeLoginRepository) DeleteLoginThrottle(key string) error {
+	delete(r.throttles, key)
+	return nil
+}
+
+func (r *fakeLoginRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	r.audit = append(r.audit, entry)
+	return nil
+}
+
+var testLoginPolicy = LoginPolicy{
+	MaxFailures:     3,
+	MaxIPFailures:   10,
+	LockoutDuration: 15 * time.Minute,
+	FailureWindow:   time.Hour,
+	BaseDelay:       time.Second,
+	MaxDelay:        8 * time.Second,
+}
+
+func newLoginTestService(repo *fakeLoginRepository) *userService {
+	return NewUserService(repo, nil, nil, nil, nil, testLoginPolicy, AccountEmails{})
+}
+
+func TestLoginDelayDoublesUpToCap(t *testing.T) {
+	assert := assert.New(t)
+	s := newLoginTestService(newFakeLoginRepository())
+
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 8 * time.Second},
+		{100, 8 * time.Second},
+	}
+	for _, test := range tests {
+		assert.Equal(test.delay, s.loginDelay(test.failures), "failures %d", test.failures)
+	}
+
+	s.loginPolicy.BaseDelay = 0
+	assert.Zero(s.loginDelay(5))
+}
+
+func TestCheckLoginThrottle(t *testing.T) {
+	now := time.Now()
+	lockedUntil := now.Add(time.Minute)
+	lockExpired := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		throttle   *models.LoginThrottle
+		retryAfter time.Duration
+		cleared    bool
+	}{
+		{name: "no failures"},
+		{
+			name:       "locked out",
+			throttle:   &models.LoginThrottle{Failures: 3, LastFailureAt: now, LockedUntil: &lockedUntil},
+			retryAfter: time.Minute,
+		},
+		{
+			name:     "lockout over",
+			throttle: &models.LoginThrottle{Failures: 3, LastFailureAt: now.Add(-time.Hour), LockedUntil: &lockExpired},
+			cleared:  true,
+		},
+		{
+			name:     "failures outside window",
+			throttle: &models.LoginThrottle{Failures: 2, LastFailureAt: now.Add(-2 * time.Hour)},
+			cleared:  true,
+		},
+		{
+			name:       "inside delay",
+			throttle:   &models.LoginThrottle{Failures: 2, LastFailureAt: now.Add(-time.Second)},
+			retryAfter: time.Second,
+		},
+		{
+			name:     "delay passed",
+			throttle: &models.LoginThrottle{Failures: 2, LastFailureAt: now.Add(-3 * time.Second)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			repo := newFakeLoginRepository()
+			if test.throttle != nil {
+				test.throttle.Key = "email:a@b.c"
+				repo.throttles[test.throttle.Key] = test.throttle
+			}
+			s := newLoginTestService(repo)
+
+			err := s.checkLoginThrottle("email:a@b.c", now)
+			if test.retryAfter == 0 {
+				assert.NoError(err)
+			} else {
+				var throttled *LoginThrottledError
+				assert.True(errors.As(err, &throttled))
+				assert.ErrorIs(err, LoginThrottled)
+				assert.Equal(test.retryAfter, throttled.RetryAfter)
+			}
+			_, kept := repo.throttles["email:a@b.c"]
+			assert.Equal(test.throttle != nil && !test.cleared, kept)
+		})
+	}
+}
+
+func TestRecordLoginFailureLocksOutAtMax(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeLoginRepository()
+	s := newLoginTestService(repo)
+	now := time.Now()
+
+	s.recordLoginFailure("email:a@b.c", 3, "10.0.0.1", now)
+	s.recordLoginFailure("email:a@b.c", 3, "10.0.0.1", now)
+	assert.Equal(2, repo.throttles["email:a@b.c"].Failures)
+	assert.Nil(repo.throttles["email:a@b.c"].LockedUntil)
+	assert.Empty(repo.audit)
+
+	s.recordLoginFailure("email:a@b.c", 3, "10.0.0.1", now)
+	lockedUntil := repo.throttles["email:a@b.c"].LockedUntil
+	if assert.NotNil(lockedUntil) {
+		assert.Equal(now.Add(testLoginPolicy.LockoutDuration), *lockedUntil)
+	}
+	if assert.Len(repo.audit, 1) {
+		assert.Equal("email:a@b.c", repo.audit[0].Subject)
+		assert.Equal("10.0.0.1", repo.audit[0].IP)
+	}
+}
+
+func TestLoginUnknownEmailCountsFailures(t *testing.T) {
+	assert := assert.New(t)
+	repo := newFakeLoginRepository()
+	s := newLoginTestService(repo)
+
+	user, err := s.Login("Nobody@Example.com", "password", "10.0.0.1")
+	assert.Nil(user)
+	assert.ErrorIs(err, InvalidCredentials)
+	assert.Equal(1, repo.throttles["email:nobody@example.com"].Failures)
+	assert.Equal(1, repo.throttles["ip:10.0.0.1"].Failures)
+
+	_, err = s.Login("nobody@example.com", "password", "10.0.0.1")
+	assert.ErrorIs(err, LoginThrottled)
+}
+
+func TestDummyPasswordHashMatchesCurrentParameters(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(auth.NeedsRehash(dummyPasswordHash))
+	assert.False(auth.VerifyPassword("password", "", dummyPasswordHash))
+}
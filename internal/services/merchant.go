@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/money"
+	"payment-payments-api/pkg/umdw"
+	"strings"
+	"time"
+)
+
+var (
+	MerchantNotFound             = errors.New("merchant not found")
+	MerchantDisabled             = errors.New("merchant is disabled")
+	InvalidMerchant              = errors.New("invalid merchant")
+	MerchantCurrencyNotSupported = errors.New("currency not supported by the merchant")
+	MerchantLimitExceeded        = errors.New("amount exceeds the merchant payment limit")
+)
+
+const maxMerchantListLimit = 100
+
+// merchantSortColumns whitelists the fields merchants can be sorted by.
+var merchantSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"name":      "name",
+}
+
+type MerchantService interface {
+	CreateMerchant(dto dtoApi.MerchantRequest) (models.Merchant, error)
+	GetMerchant(id uuid.UUID) (models.Merchant, error)
+	UpdateMerchant(id uuid.UUID, dto dtoApi.MerchantRequest) (models.Merchant, error)
+	ListMerchants(list umdw.List) ([]dtoApi.MerchantResponse, int64, error)
+	SetMerchantEnabled(id uuid.UUID, enabled bool) (models.Merchant, error)
+	CheckPayment(merchantID string, amount money.Money) (models.Merchant, error)
+}
+
+type merchantService struct {
+	merchantRepository repositories.MerchantRepository
+}
+
+func NewMerchantService(merchantRepository repositories.MerchantRepository) *merchantService {
+	return &merchantService{merchantRepository: merchantRepository}
+}
+
+// CreateMerchant onboards a merchant. New merchants are enabled.
+func (s *merchantService) CreateMerchant(request dtoApi.MerchantRequest) (models.Merchant, error) {
+	merchant := models.Merchant{Enabled: true, CreatedAt: time.Now()}
+	if err := applyMerchantRequest(&merchant, request); err != nil {
+		return merchant, err
+	}
+	return s.merchantRepository.CreateMerchant(merchant)
+}
+
+func (s *merchantService) GetMerchant(id uuid.UUID) (models.Merchant, error) {
+	merchant, err := s.merchantRepository.GetMerchantByID(id)
+	if err != nil {
+		return merchant, MerchantNotFound
+	}
+	return merchant, nil
+}
+
+func (s *merchantService) UpdateMerchant(id uuid.UUID, request dtoApi.MerchantRequest) (models.Merchant, error) {
+	merchant, err := s.GetMerchant(id)
+	if err != nil {
+		return merchant, err
+	}
+	if err = applyMerchantRequest(&merchant, request); err != nil {
+		return merchant, err
+	}
+	merchant.UpdatedAt = time.Now()
+	return s.merchantRepository.UpdateMerchant(merchant)
+}
+
+func (s *merchantService) ListMerchants(list umdw.List) ([]dtoApi.MerchantResponse, int64, error) {
+	if list.Limit > maxMerchantListLimit {
+		return nil, 0, fmt.Errorf("%w: limit must be at most %d", InvalidMerchant, maxMerchantListLimit)
+	}
+	if list.By == "" {
+		list.By = "createdAt"
+	}
+	column, ok := merchantSortColumns[list.By]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: cannot sort by %q", InvalidMerchant, list.By)
+	}
+	list.By = column
+
+	merchants, total, err := s.merchantRepository.ListMerchants(list)
+	if err != nil {
+		return nil, 0, err
+	}
+	dtos := make([]dtoApi.MerchantResponse, len(merchants))
+	for i := range merchants {
+		dtos[i] = dtoApi.MapMerchantToMerchantResponse(&merchants[i])
+	}
+	return dtos, total, nil
+}
+
+func (s *merchantService) SetMerchantEnabled(id uuid.UUID, enabled bool) (models.Merchant, error) {
+	merchant, err := s.GetMerchant(id)
+	if err != nil {
+		return merchant, err
+	}
+	merchant.Enabled = enabled
+	merchant.UpdatedAt = time.Now()
+	return s.merchantRepository.UpdateMerchant(merchant)
+}
+
+// CheckPayment returns the merchant a payment of amount is made to, or an
+// error when the merchant is unknown, disabled, does not take the currency
+// or caps payments below amount.
+func (s *merchantService) CheckPayment(merchantID string, amount money.Money) (models.Merchant, error) {
+	id, err := uuid.Parse(merchantID)
+	if err != nil {
+		return models.Merchant{}, MerchantNotFound
+	}
+	merchant, err := s.GetMerchant(id)
+	if err != nil {
+		return merchant, err
+	}
+	if !merchant.Enabled {
+		return merchant, MerchantDisabled
+	}
+	if !merchant.SupportsCurrency(amount.Currency) {
+		return merchant, MerchantCurrencyNotSupported
+	}
+	if limit, ok := merchant.PaymentLimit(amount.Currency); ok && amount.Minor > limit.Minor {
+		return merchant, MerchantLimitExceeded
+	}
+	return merchant, nil
+}
+
+func applyMerchantRequest(merchant *models.Merchant, request dtoApi.MerchantRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", InvalidMerchant)
+	}
+	if len(request.Currencies) == 0 {
+		return fmt.Errorf("%w: at least one currency is required", InvalidMerchant)
+	}
+
+	currencies := make([]string, 0, len(request.Currencies))
+	for _, currency := range request.Currencies {
+		if !money.IsSupported(currency) {
+			return fmt.Errorf("%w: unknown currency %q", InvalidMerchant, currency)
+		}
+		currency = strings.ToUpper(currency)
+		if !containsString(currencies, currency) {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	limits := make([]money.Money, 0, len(request.PaymentLimits))
+	for _, limit := range request.PaymentLimits {
+		amount, err := parseAmount(limit.Amount, limit.Currency)
+		if err != nil {
+			return fmt.Errorf("%w: payment limit: %v", InvalidMerchant, err)
+		}
+		if !containsString(currencies, amount.Currency) {
+			return fmt.Errorf("%w: payment limit in unsupported currency %s", InvalidMerchant, amount.Currency)
+		}
+		limits = append(limits, amount)
+	}
+
+	merchant.Name = name
+	merchant.Currencies = currencies
+	merchant.PaymentLimits = limits
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
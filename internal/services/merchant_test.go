@@ -0,0 +1,68 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/money"
+	"testing"
+)
+
+type fakeMerchantRepository struct {
+	repositories.MerchantRepository
+	merchants map[uuid.UUID]models.Merchant
+}
+
+func (r *fakeMerchantRepository) GetMerchantByID(id uuid.UUID) (models.Merchant, error) {
+	merchant, ok := r.merchants[id]
+	if !ok {
+		return merchant, gorm.ErrRecordNotFound
+	}
+	return merchant, nil
+}
+
+func TestCheckPayment(t *testing.T) {
+	enabled := models.Merchant{
+		ID:            uuid.New(),
+		Name:          "Acme",
+		Enabled:       true,
+		Currencies:    []string{"USD", "EUR", "CLP"},
+		PaymentLimits: []money.Money{money.New(10000, "USD"), money.New(500000, "CLP")},
+	}
+	disabled := models.Merchant{ID: uuid.New(), Name: "Closed", Currencies: []string{"USD"}}
+	s := NewMerchantService(&fakeMerchantRepository{merchants: map[uuid.UUID]models.Merchant{
+		enabled.ID:  enabled,
+		disabled.ID: disabled,
+	}})
+
+	tests := []struct {
+		name       string
+		merchantID string
+		amount     money.Money
+		err        error
+	}{
+		{"under the limit", enabled.ID.String(), money.New(9999, "USD"), nil},
+		{"equal to the limit", enabled.ID.String(), money.New(10000, "USD"), nil},
+		{"over the limit", enabled.ID.String(), money.New(10001, "USD"), MerchantLimitExceeded},
+		{"over the limit of another currency", enabled.ID.String(), money.New(600000, "CLP"), MerchantLimitExceeded},
+		{"limit in another currency only", enabled.ID.String(), money.New(10000000, "EUR"), nil},
+		{"unsupported currency", enabled.ID.String(), money.New(100, "GBP"), MerchantCurrencyNotSupported},
+		{"disabled merchant", disabled.ID.String(), money.New(100, "USD"), MerchantDisabled},
+		{"unknown merchant", uuid.NewString(), money.New(100, "USD"), MerchantNotFound},
+		{"invalid merchant ID", "acme", money.New(100, "USD"), MerchantNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			merchant, err := s.CheckPayment(test.merchantID, test.amount)
+
+			assert.Equal(test.err, err)
+			if test.err == nil {
+				assert.Equal(enabled.ID, merchant.ID)
+			}
+		})
+	}
+}
@@ -2,60 +2,139 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	dtoApi "payment-payments-api/internal/api/dto"
 	dtoKafka "payment-payments-api/internal/kafka/dto"
-	"payment-payments-api/internal/kafka/producer"
 	"payment-payments-api/internal/models"
 	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/money"
+	"payment-payments-api/pkg/umdw"
+	"strings"
 	"time"
 )
 
 var (
 	PaymentAlreadyRefunded = errors.New("payment already refunded")
 	PaymentNotFound        = errors.New("payment not found")
+	InvalidPaymentID       = errors.New("invalid payment id")
+	InvalidAmount          = errors.New("invalid amount")
+	InvalidPaymentFilter   = errors.New("invalid payment filter")
+	DuplicatePaymentEvent  = errors.New("payment event already processed")
 )
 
+const maxPaymentListLimit = 100
+
+// paymentSortColumns whitelists the fields payments can be sorted by.
+var paymentSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"amount":    "amount_minor",
+	"status":    "status",
+}
+
 type PaymentService interface {
 	CreatePayment(dto dtoApi.PaymentRequest) (models.Payment, error)
-	RefundPayment(dto dtoApi.RefundRequest) (models.Payment, error)
+	RefundPayment(dto dtoApi.RefundRequest) (models.Refund, error)
+	CapturePayment(id uuid.UUID, dto dtoApi.CaptureRequest) (models.Payment, error)
+	VoidPayment(id uuid.UUID) (models.Payment, error)
+	ExpireAuthorizations() (int64, error)
 	GetPaymentByID(id uuid.UUID) (dtoApi.PaymentResponse, error)
+	GetPaymentByTransactionID(transactionID string) (dtoApi.PaymentResponse, error)
+	GetPaymentEvents(id uuid.UUID) ([]dtoApi.PaymentEventResponse, error)
+	ListPayments(dto dtoApi.PaymentListRequest, list umdw.List) ([]dtoApi.PaymentResponse, int64, error)
+	ListPaymentsByCursor(dto dtoApi.PaymentListRequest, list umdw.List, cursor *umdw.Cursor) ([]dtoApi.PaymentResponse, umdw.KeysetPage, error)
 	UpdatePayment(payment dtoKafka.PaymentResponse) error
 }
 
 type paymentService struct {
-	paymentProducer   producer.PaymentProducer
-	paymentRepository repositories.PaymentRepository
+	paymentRepository      repositories.PaymentRepository
+	paymentEventRepository repositories.PaymentEventRepository
+	refundRepository       repositories.RefundRepository
+	transactor             repositories.Transactor
+	cardService            CardService
+	ledgerService          LedgerService
+	merchantService        MerchantService
+	authorizationWindow    time.Duration
 }
 
 func NewPaymentService(paymentRepository repositories.PaymentRepository,
-	paymentProducer producer.PaymentProducer) *paymentService {
+	paymentEventRepository repositories.PaymentEventRepository,
+	refundRepository repositories.RefundRepository,
+	transactor repositories.Transactor,
+	cardService CardService,
+	ledgerService LedgerService,
+	merchantService MerchantService,
+	authorizationWindow time.Duration) *paymentService {
 	return &paymentService{paymentRepository: paymentRepository,
-		paymentProducer: paymentProducer,
+		paymentEventRepository: paymentEventRepository,
+		refundRepository:       refundRepository,
+		transactor:             transactor,
+		cardService:            cardService,
+		ledgerService:          ledgerService,
+		merchantService:        merchantService,
+		authorizationWindow:    authorizationWindow,
 	}
 }
 
 func (s *paymentService) CreatePayment(paymentRequest dtoApi.PaymentRequest) (models.Payment, error) {
+	amount, err := parseAmount(paymentRequest.Amount, paymentRequest.Currency)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	merchant, err := s.merchantService.CheckPayment(paymentRequest.MerchantID, amount)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	card, err := s.cardService.GetCard(paymentRequest.CardToken, paymentRequest.UserID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	now := time.Now()
 	payment := models.Payment{
-		CardID:     paymentRequest.CardID,
-		Amount:     paymentRequest.Amount,
-		Currency:   paymentRequest.Currency,
-		Merchant:   paymentRequest.Merchant,
-		MerchantID: paymentRequest.MerchantID,
-		UserID:     paymentRequest.UserID,
-		Status:     enums.Pending,
-		CreatedAt:  time.Now(),
-	}
-	model, err := s.paymentRepository.CreatePayment(payment)
-	if err != nil {
-		return model, err
-	}
-	dto := dtoKafka.MapPaymentRequestToPaymentRequest(paymentRequest)
-	dto.PaymentID = model.ID.String()
-	dto.Status = model.Status
-	dto.Type = enums.Payment
-	err = s.paymentProducer.Produce(dto)
+		CardToken:        card.Token,
+		CardBin:          card.Bin,
+		CardLast4:        card.Last4,
+		Amount:           amount,
+		RefundedAmount:   money.New(0, amount.Currency),
+		AuthorizedAmount: amount,
+		Merchant:         merchant.Name,
+		MerchantID:       merchant.ID.String(),
+		UserID:           paymentRequest.UserID,
+		Status:           enums.Pending,
+		CreatedAt:        now,
+	}
+	paymentType := enums.PaymentType(enums.Payment)
+	if paymentRequest.Capture != nil && !*paymentRequest.Capture {
+		expiresAt := now.Add(s.authorizationWindow)
+		payment.AuthorizationExpiresAt = &expiresAt
+		paymentType = enums.Authorize
+	}
+	var model models.Payment
+	err = s.transactor.Transaction(func(r repositories.TxRepositories) error {
+		var err error
+		model, err = r.Payment.CreatePayment(payment)
+		if err != nil {
+			return err
+		}
+		dto := dtoKafka.PaymentRequest{
+			PaymentID: model.ID.String(),
+			Merchant:  model.Merchant,
+		}
+		dto.SetAmount(model.Amount)
+		dto.EncryptedCard, err = s.cardService.SealForBank(card, paymentRequest.CVC, model.ID)
+		if err != nil {
+			return err
+		}
+		dto.Status = model.Status
+		dto.Type = paymentType
+		err = recordPaymentEvent(r.PaymentEvent, model, "", enums.EventSourceCreate, "")
+		if err != nil {
+			return err
+		}
+		return enqueuePaymentRequest(r.Outbox, model, dto)
+	})
 	return model, err
 }
 
@@ -65,44 +144,319 @@ func (s *paymentService) GetPaymentByID(id uuid.UUID) (dtoApi.PaymentResponse, e
 	if err != nil {
 		return dto, PaymentNotFound
 	}
+	refunds, err := s.refundRepository.GetRefundsByPaymentID(id)
+	if err != nil {
+		return dto, err
+	}
 	dto = dtoApi.MapPaymenToPaymentResponse(&model)
+	dto.Refunds = make([]dtoApi.RefundResponse, len(refunds))
+	for i := range refunds {
+		dto.Refunds[i] = dtoApi.MapRefundToRefundResponse(&refunds[i])
+	}
 	return dto, nil
 }
 
-func (s *paymentService) RefundPayment(request dtoApi.RefundRequest) (models.Payment, error) {
-	model, err := s.paymentRepository.GetPaymentByTransactionID(request.TransactionID)
+func (s *paymentService) GetPaymentByTransactionID(transactionID string) (dtoApi.PaymentResponse, error) {
+	model, err := s.paymentRepository.GetPaymentByTransactionID(transactionID)
+	if err != nil {
+		return dtoApi.PaymentResponse{}, PaymentNotFound
+	}
+	return dtoApi.MapPaymenToPaymentResponse(&model), nil
+}
+
+func (s *paymentService) GetPaymentEvents(id uuid.UUID) ([]dtoApi.PaymentEventResponse, error) {
+	_, err := s.paymentRepository.GetPaymentByID(id)
 	if err != nil {
-		return model, PaymentNotFound
+		return nil, PaymentNotFound
 	}
-	if model.Status == enums.Cancelled {
-		return model, PaymentAlreadyRefunded
+	events, err := s.paymentEventRepository.GetPaymentEventsByPaymentID(id)
+	if err != nil {
+		return nil, err
 	}
-	dto := dtoKafka.PaymentRequest{
-		PaymentID:     model.ID.String(),
-		TransactionID: request.TransactionID,
-		Type:          enums.Refund,
-		Status:        model.Status,
-		Amount:        request.Amount,
-		Currency:      request.Currency,
+	dtos := make([]dtoApi.PaymentEventResponse, len(events))
+	for i := range events {
+		dtos[i] = dtoApi.MapPaymentEventToPaymentEventResponse(&events[i])
 	}
-	err = s.paymentProducer.Produce(dto)
-	return model, err
+	return dtos, nil
+}
+
+func (s *paymentService) ListPayments(request dtoApi.PaymentListRequest, list umdw.List) ([]dtoApi.PaymentResponse, int64, error) {
+	filter, err := paymentFilter(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	if list.Limit > maxPaymentListLimit {
+		return nil, 0, fmt.Errorf("%w: limit must be at most %d", InvalidPaymentFilter, maxPaymentListLimit)
+	}
+	if list.By == "" {
+		list.By = "createdAt"
+	}
+	column, ok := paymentSortColumns[list.By]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: cannot sort by %q", InvalidPaymentFilter, list.By)
+	}
+	list.By = column
+
+	payments, total, err := s.paymentRepository.ListPayments(filter, list)
+	if err != nil {
+		return nil, 0, err
+	}
+	dtos := make([]dtoApi.PaymentResponse, len(payments))
+	for i := range payments {
+		dtos[i] = dtoApi.MapPaymenToPaymentResponse(&payments[i])
+	}
+	return dtos, total, nil
+}
+
+// ListPaymentsByCursor pages through payments in created-at order starting
+// at cursor, which is nil for the first page. The order requested for the
+// first page is kept by the cursors handed out after it.
+func (s *paymentService) ListPaymentsByCursor(request dtoApi.PaymentListRequest, list umdw.List,
+	cursor *umdw.Cursor) ([]dtoApi.PaymentResponse, umdw.KeysetPage, error) {
+	filter, err := paymentFilter(request)
+	if err != nil {
+		return nil, umdw.KeysetPage{}, err
+	}
+	if list.Limit > maxPaymentListLimit {
+		return nil, umdw.KeysetPage{}, fmt.Errorf("%w: limit must be at most %d", InvalidPaymentFilter, maxPaymentListLimit)
+	}
+	if list.By != "" && list.By != "createdAt" {
+		return nil, umdw.KeysetPage{}, fmt.Errorf("%w: cursor paging only sorts by createdAt", InvalidPaymentFilter)
+	}
+	desc := list.Sort == "desc"
+	if cursor != nil {
+		desc = cursor.Desc
+	}
+
+	payments, err := s.paymentRepository.ListPaymentsByCursor(filter, cursor, desc, list.Limit+1)
+	if err != nil {
+		return nil, umdw.KeysetPage{}, err
+	}
+	hasMore := len(payments) > list.Limit
+	if hasMore {
+		payments = payments[:list.Limit]
+	}
+	if cursor != nil && cursor.Before {
+		for i, j := 0, len(payments)-1; i < j; i, j = i+1, j-1 {
+			payments[i], payments[j] = payments[j], payments[i]
+		}
+	}
+
+	var first, last *umdw.Cursor
+	if len(payments) > 0 {
+		first = &umdw.Cursor{CreatedAt: payments[0].CreatedAt, ID: payments[0].ID.String()}
+		last = &umdw.Cursor{CreatedAt: payments[len(payments)-1].CreatedAt, ID: payments[len(payments)-1].ID.String()}
+	}
+	dtos := make([]dtoApi.PaymentResponse, len(payments))
+	for i := range payments {
+		dtos[i] = dtoApi.MapPaymenToPaymentResponse(&payments[i])
+	}
+	return dtos, umdw.NewKeysetPage(cursor, desc, hasMore, first, last), nil
 }
 
+// UpdatePayment applies a status update from the bank. Updates that the
+// status transition table does not allow are recorded as rejected transitions
+// and returned as *enums.TransitionError without touching the payment. An
+// update whose event ID was already applied to the payment returns
+// DuplicatePaymentEvent and changes nothing.
 func (s *paymentService) UpdatePayment(dto dtoKafka.PaymentResponse) error {
 	id, err := uuid.Parse(dto.PaymentID)
+	if err != nil {
+		return InvalidPaymentID
+	}
+	status, err := enums.Parse(dto.Status)
 	if err != nil {
 		return err
 	}
-	payment, err := s.paymentRepository.GetPaymentByID(id)
+
+	var transitionErr error
+	err = s.transactor.Transaction(func(r repositories.TxRepositories) error {
+		payment, err := r.Payment.GetPaymentByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if dto.EventID != "" {
+			created, err := r.Payment.CreateProcessedEvent(models.ProcessedEvent{
+				PaymentID:   payment.ID,
+				EventID:     dto.EventID,
+				ProcessedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			if !created {
+				return DuplicatePaymentEvent
+			}
+		}
+		if dto.RefundReference != "" || dto.RefundID != "" {
+			transitionErr, err = s.applyRefundUpdate(r, payment, status, dto)
+			return err
+		}
+		previous := payment.Status
+		status := previous.Resolve(status)
+		if status != previous {
+			if transitionErr = previous.Transition(status); transitionErr != nil {
+				return rejectTransition(r, payment, status, dto)
+			}
+		}
+		if previous == enums.CapturePending && status != previous {
+			settleCapture(&payment, status)
+		}
+		payment.Status = status
+		payment.TransactionID = dto.TransactionID
+		payment.Msg = dto.Msg
+		payment.UpdatedAt = time.Now()
+		payment, err = r.Payment.UpdatePayment(payment)
+		if err != nil || status == previous {
+			return err
+		}
+		if err = s.recordLedger(r, payment, previous); err != nil {
+			return err
+		}
+		return recordPaymentEvent(r.PaymentEvent, payment, previous, enums.EventSourceBank, dto.Msg)
+	})
 	if err != nil {
 		return err
 	}
-	payment.Status = enums.Parse(dto.Status)
-	payment.TransactionID = dto.TransactionID
-	payment.Msg = dto.Msg
-	payment.RefundID = dto.RefundID
-	payment.UpdatedAt = time.Now()
-	_, err = s.paymentRepository.UpdatePayment(payment)
+	return transitionErr
+}
+
+// rejectTransition records a bank update that would have moved payment to
+// status, which the transition table does not allow.
+func rejectTransition(r repositories.TxRepositories, payment models.Payment, status enums.PaymentStatus,
+	dto dtoKafka.PaymentResponse) error {
+	return r.Payment.CreateRejectedTransition(models.RejectedTransition{
+		PaymentID:     payment.ID,
+		FromStatus:    payment.Status,
+		ToStatus:      status,
+		TransactionID: dto.TransactionID,
+		Msg:           dto.Msg,
+		CreatedAt:     time.Now(),
+	})
+}
+
+// settleCapture applies the bank's answer to a pending capture: an approved
+// capture's amount becomes the payment amount. Either way the pending capture
+// amount is cleared.
+func settleCapture(payment *models.Payment, status enums.PaymentStatus) {
+	if status == enums.Approved && payment.CaptureAmount.Currency != "" {
+		payment.Amount = payment.CaptureAmount
+	}
+	payment.CaptureAmount = money.Money{}
+}
+
+// recordLedger books the money movement of a bank-confirmed status change.
+// Approved only counts as a capture when coming from a pre-capture status;
+// an Approved payment returning from RefundPending was already booked.
+func (s *paymentService) recordLedger(r repositories.TxRepositories, payment models.Payment, previous enums.PaymentStatus) error {
+	switch {
+	case payment.Status == enums.Approved &&
+		(previous == enums.Pending || previous == enums.InProgress || previous == enums.CapturePending):
+		return s.ledgerService.RecordPayment(r.Ledger, payment)
+	case payment.Status == enums.ChargedBack:
+		amount, err := payment.Amount.Sub(payment.RefundedAmount)
+		if err != nil {
+			return err
+		}
+		return s.ledgerService.RecordChargeback(r.Ledger, payment, amount)
+	}
+	return nil
+}
+
+// enqueuePaymentRequest stores message in the outbox so it is published to the
+// bank only if the surrounding transaction commits.
+func enqueuePaymentRequest(outbox repositories.OutboxRepository, payment models.Payment, message dtoKafka.PaymentRequest) error {
+	outboxMessage, err := models.NewOutboxMessage(payment.ID, message)
+	if err != nil {
+		return err
+	}
+	_, err = outbox.CreateOutboxMessage(outboxMessage)
 	return err
 }
+
+// parseAmount converts a client decimal into Money, rejecting amounts that
+// are not positive or do not fit the currency's exponent.
+func parseAmount(amount money.Decimal, currency string) (money.Money, error) {
+	m, err := amount.Money(currency)
+	if err != nil {
+		return m, fmt.Errorf("%w: %v", InvalidAmount, err)
+	}
+	if !m.IsPositive() {
+		return m, fmt.Errorf("%w: must be greater than 0", InvalidAmount)
+	}
+	return m, nil
+}
+
+// recordPaymentEvent appends the move of payment from previous to its current
+// status to the payment timeline.
+func recordPaymentEvent(events repositories.PaymentEventRepository, payment models.Payment,
+	previous enums.PaymentStatus, source enums.PaymentEventSource, msg string) error {
+	_, err := events.CreatePaymentEvent(models.PaymentEvent{
+		PaymentID:      payment.ID,
+		Source:         source,
+		PreviousStatus: previous,
+		NewStatus:      payment.Status,
+		Msg:            msg,
+		CreatedAt:      time.Now(),
+	})
+	return err
+}
+
+// paymentFilter validates the list query and converts it to repository terms.
+func paymentFilter(request dtoApi.PaymentListRequest) (repositories.PaymentFilter, error) {
+	filter := repositories.PaymentFilter{
+		UserID:     request.UserID,
+		MerchantID: request.MerchantID,
+	}
+	if request.Status != "" {
+		status, err := enums.Parse(request.Status)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %v", InvalidPaymentFilter, err)
+		}
+		filter.Status = status
+	}
+	if request.Currency != "" {
+		if !money.IsSupported(request.Currency) {
+			return filter, fmt.Errorf("%w: unknown currency %q", InvalidPaymentFilter, request.Currency)
+		}
+		filter.Currency = strings.ToUpper(request.Currency)
+	}
+	if (request.MinAmount != "" || request.MaxAmount != "") && filter.Currency == "" {
+		return filter, fmt.Errorf("%w: currency is required to filter by amount", InvalidPaymentFilter)
+	}
+	for _, bound := range []struct {
+		value money.Decimal
+		dest  **int64
+		name  string
+	}{
+		{request.MinAmount, &filter.MinAmount, "minAmount"},
+		{request.MaxAmount, &filter.MaxAmount, "maxAmount"},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		amount, err := bound.value.Money(filter.Currency)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %s: %v", InvalidPaymentFilter, bound.name, err)
+		}
+		*bound.dest = &amount.Minor
+	}
+	for _, bound := range []struct {
+		value string
+		dest  **time.Time
+		name  string
+	}{
+		{request.CreatedFrom, &filter.CreatedFrom, "createdFrom"},
+		{request.CreatedTo, &filter.CreatedTo, "createdTo"},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %s must be an RFC 3339 date", InvalidPaymentFilter, bound.name)
+		}
+		*bound.dest = &t
+	}
+	return filter, nil
+}
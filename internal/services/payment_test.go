@@ -0,0 +1,38 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+	"testing"
+)
+
+func TestSettleCapture(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture money.Money
+		status  enums.PaymentStatus
+		amount  money.Money
+	}{
+		{"approved", money.New(600, "USD"), enums.Approved, money.New(600, "USD")},
+		{"failed", money.New(600, "USD"), enums.Authorized, money.New(1000, "USD")},
+		{"approved without capture amount", money.Money{}, enums.Approved, money.New(1000, "USD")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			payment := models.Payment{
+				Amount:           money.New(1000, "USD"),
+				AuthorizedAmount: money.New(1000, "USD"),
+				CaptureAmount:    test.capture,
+			}
+
+			settleCapture(&payment, test.status)
+
+			assert.Equal(test.amount, payment.Amount)
+			assert.Equal(money.Money{}, payment.CaptureAmount)
+			assert.Equal(money.New(1000, "USD"), payment.AuthorizedAmount)
+		})
+	}
+}
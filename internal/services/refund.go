@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	dtoApi "payment-payments-api/internal/api/dto"
+	dtoKafka "payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/money"
+	"time"
+)
+
+var (
+	RefundCurrencyMismatch = errors.New("refund currency does not match the payment currency")
+	RefundExceedsBalance   = errors.New("refund amount exceeds the remaining refundable balance")
+	RefundNotFound         = errors.New("refund not found")
+)
+
+// RefundPayment creates a pending refund for part or all of the remaining
+// captured amount and queues it for the bank. The payment row stays locked
+// while the balance is checked so concurrent refunds cannot overdraw it.
+func (s *paymentService) RefundPayment(request dtoApi.RefundRequest) (models.Refund, error) {
+	var refund models.Refund
+	err := s.transactor.Transaction(func(r repositories.TxRepositories) error {
+		payment, err := r.Payment.GetPaymentByTransactionIDForUpdate(request.TransactionID)
+		if err != nil {
+			return PaymentNotFound
+		}
+		if payment.Status == enums.Cancelled || payment.Status == enums.Refunded {
+			return PaymentAlreadyRefunded
+		}
+		amount, err := parseAmount(request.Amount, request.Currency)
+		if err != nil {
+			return err
+		}
+		if !amount.SameCurrency(payment.Amount) {
+			return RefundCurrencyMismatch
+		}
+
+		refunds, err := r.Refund.GetRefundsByPaymentID(payment.ID)
+		if err != nil {
+			return err
+		}
+		if err = checkRefundBalance(payment, refunds, amount); err != nil {
+			return err
+		}
+
+		previous := payment.Status
+		if previous != enums.RefundPending {
+			if err = previous.Transition(enums.RefundPending); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		refund, err = r.Refund.CreateRefund(models.Refund{
+			PaymentID: payment.ID,
+			Amount:    amount,
+			Status:    enums.RefundStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		dto := dtoKafka.PaymentRequest{
+			PaymentID:       payment.ID.String(),
+			TransactionID:   request.TransactionID,
+			Type:            enums.Refund,
+			Status:          previous,
+			RefundReference: refund.ID.String(),
+		}
+		dto.SetAmount(amount)
+
+		payment.Status = enums.RefundPending
+		payment.UpdatedAt = now
+		payment, err = r.Payment.UpdatePayment(payment)
+		if err != nil {
+			return err
+		}
+		if previous != payment.Status {
+			err = recordPaymentEvent(r.PaymentEvent, payment, previous, enums.EventSourceRefund, "")
+			if err != nil {
+				return err
+			}
+		}
+		return enqueuePaymentRequest(r.Outbox, payment, dto)
+	})
+	return refund, err
+}
+
+// applyRefundUpdate settles the refund a bank update refers to and derives the
+// payment status from the refunded total and the refunds still pending.
+// Redelivered updates for a settled refund only fill in the bank refund ID.
+// A payment status the refund cannot move to is recorded as a rejected
+// transition and returned as transitionErr, like UpdatePayment does.
+func (s *paymentService) applyRefundUpdate(r repositories.TxRepositories, payment models.Payment,
+	status enums.PaymentStatus, dto dtoKafka.PaymentResponse) (transitionErr error, err error) {
+	refunds, err := r.Refund.GetRefundsByPaymentID(payment.ID)
+	if err != nil {
+		return nil, err
+	}
+	refund, ok := matchRefund(refunds, dto)
+	if !ok {
+		return nil, RefundNotFound
+	}
+
+	if dto.RefundID != "" {
+		refund.BankRefundID = dto.RefundID
+	}
+	refund.Msg = dto.Msg
+	refund.UpdatedAt = time.Now()
+
+	if !refund.IsPending() {
+		_, err = r.Refund.UpdateRefund(refund)
+		return nil, err
+	}
+
+	if refund.Status, err = refundOutcome(status); err != nil {
+		return nil, err
+	}
+	switch refund.Status {
+	case enums.RefundStatusPending:
+		_, err = r.Refund.UpdateRefund(refund)
+		return nil, err
+	case enums.RefundStatusSucceeded:
+		if payment.RefundedAmount, err = payment.RefundedAmount.Add(refund.Amount); err != nil {
+			return nil, err
+		}
+	}
+	if refund, err = r.Refund.UpdateRefund(refund); err != nil {
+		return nil, err
+	}
+	if refund.Status == enums.RefundStatusSucceeded {
+		if err = s.ledgerService.RecordRefund(r.Ledger, payment, refund); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range refunds {
+		if refunds[i].ID == refund.ID {
+			refunds[i] = refund
+		}
+	}
+
+	next := refundedStatus(payment, refunds)
+	if next != payment.Status {
+		if transitionErr = payment.Status.RefundTransition(next); transitionErr != nil {
+			if err = rejectTransition(r, payment, next, dto); err != nil {
+				return nil, err
+			}
+			next = payment.Status
+		}
+	}
+	previous := payment.Status
+	payment.Status = next
+	payment.UpdatedAt = time.Now()
+	payment, err = r.Payment.UpdatePayment(payment)
+	if err != nil || next == previous {
+		return transitionErr, err
+	}
+	return nil, recordPaymentEvent(r.PaymentEvent, payment, previous, enums.EventSourceBank, dto.Msg)
+}
+
+// refundOutcome maps the payment status of a bank update to the status of the
+// refund it answers. Statuses that neither settle nor fail a refund, such as
+// Cancelled or ChargedBack, are rejected instead of booked as a refund.
+func refundOutcome(status enums.PaymentStatus) (enums.RefundStatus, error) {
+	switch status {
+	case enums.Pending, enums.InProgress, enums.RefundPending:
+		return enums.RefundStatusPending, nil
+	case enums.Failed:
+		return enums.RefundStatusFailed, nil
+	case enums.Refunded, enums.PartiallyRefunded, enums.Approved:
+		return enums.RefundStatusSucceeded, nil
+	default:
+		return "", fmt.Errorf("%w: %q for a refund", enums.InvalidStatus, string(status))
+	}
+}
+
+// matchRefund finds the refund a bank update belongs to: by our refund
+// reference, then by the bank refund ID. Only an update that carries neither
+// falls back to the oldest pending refund, so an unknown ID never settles or
+// relabels another refund.
+func matchRefund(refunds []models.Refund, dto dtoKafka.PaymentResponse) (models.Refund, bool) {
+	if reference, err := uuid.Parse(dto.RefundReference); err == nil {
+		for _, refund := range refunds {
+			if refund.ID == reference {
+				return refund, true
+			}
+		}
+		return models.Refund{}, false
+	}
+	if dto.RefundID != "" {
+		for _, refund := range refunds {
+			if refund.BankRefundID == dto.RefundID {
+				return refund, true
+			}
+		}
+		return models.Refund{}, false
+	}
+	if dto.RefundReference != "" {
+		return models.Refund{}, false
+	}
+	for _, refund := range refunds {
+		if refund.IsPending() {
+			return refund, true
+		}
+	}
+	return models.Refund{}, false
+}
+
+// checkRefundBalance rejects a refund of amount that, with what was refunded
+// and what pending refunds reserve, would exceed the captured amount.
+func checkRefundBalance(payment models.Payment, refunds []models.Refund, amount money.Money) error {
+	if payment.RefundedAmount.Minor+pendingRefundAmount(refunds)+amount.Minor > payment.Amount.Minor {
+		return RefundExceedsBalance
+	}
+	return nil
+}
+
+// pendingRefundAmount returns the minor units reserved by refunds still awaiting the bank.
+func pendingRefundAmount(refunds []models.Refund) int64 {
+	var amount int64
+	for _, refund := range refunds {
+		if refund.IsPending() {
+			amount += refund.Amount.Minor
+		}
+	}
+	return amount
+}
+
+func refundedStatus(payment models.Payment, refunds []models.Refund) enums.PaymentStatus {
+	switch {
+	case pendingRefundAmount(refunds) > 0:
+		return enums.RefundPending
+	case payment.RefundedAmount.Minor >= payment.Amount.Minor:
+		return enums.Refunded
+	case payment.RefundedAmount.IsPositive():
+		return enums.PartiallyRefunded
+	default:
+		return enums.Approved
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	dtoKafka "payment-payments-api/internal/kafka/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/money"
+	"testing"
+	"time"
+)
+
+func testRefund(minor int64, status enums.RefundStatus, bankRefundID string, age time.Duration) models.Refund {
+	return models.Refund{
+		ID:           uuid.New(),
+		Amount:       money.New(minor, "USD"),
+		Status:       status,
+		BankRefundID: bankRefundID,
+		CreatedAt:    time.Now().Add(-age),
+	}
+}
+
+func TestMatchRefund(t *testing.T) {
+	settled := testRefund(100, enums.RefundStatusSucceeded, "bank-1", 3*time.Hour)
+	oldest := testRefund(200, enums.RefundStatusPending, "", 2*time.Hour)
+	newest := testRefund(300, enums.RefundStatusPending, "bank-3", time.Hour)
+	refunds := []models.Refund{settled, oldest, newest}
+
+	tests := []struct {
+		name  string
+		dto   dtoKafka.PaymentResponse
+		want  models.Refund
+		found bool
+	}{
+		{"by reference", dtoKafka.PaymentResponse{RefundReference: newest.ID.String(), RefundID: "bank-1"}, newest, true},
+		{"settled by reference", dtoKafka.PaymentResponse{RefundReference: settled.ID.String()}, settled, true},
+		{"unknown reference", dtoKafka.PaymentResponse{RefundReference: uuid.NewString(), RefundID: "bank-3"}, models.Refund{}, false},
+		{"by bank refund ID", dtoKafka.PaymentResponse{RefundID: "bank-3"}, newest, true},
+		{"invalid reference falls back to bank ID", dtoKafka.PaymentResponse{RefundReference: "ref", RefundID: "bank-1"}, settled, true},
+		{"invalid reference", dtoKafka.PaymentResponse{RefundReference: "ref"}, models.Refund{}, false},
+		{"unknown bank refund ID", dtoKafka.PaymentResponse{RefundID: "bank-9"}, models.Refund{}, false},
+		{"oldest pending", dtoKafka.PaymentResponse{}, oldest, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			refund, found := matchRefund(refunds, test.dto)
+
+			assert.Equal(test.found, found)
+			assert.Equal(test.want.ID, refund.ID)
+		})
+	}
+
+	t.Run("nothing pending", func(t *testing.T) {
+		_, found := matchRefund([]models.Refund{settled}, dtoKafka.PaymentResponse{})
+		assert.False(t, found)
+	})
+}
+
+func TestPendingRefundAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		refunds []models.Refund
+		amount  int64
+	}{
+		{"no refunds", nil, 0},
+		{"settled only", []models.Refund{
+			testRefund(100, enums.RefundStatusSucceeded, "", 0),
+			testRefund(200, enums.RefundStatusFailed, "", 0),
+		}, 0},
+		{"pending", []models.Refund{
+			testRefund(100, enums.RefundStatusPending, "", 0),
+			testRefund(200, enums.RefundStatusSucceeded, "", 0),
+			testRefund(300, enums.RefundStatusPending, "", 0),
+		}, 400},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.amount, pendingRefundAmount(test.refunds))
+		})
+	}
+}
+
+func TestRefundedStatus(t *testing.T) {
+	pending := []models.Refund{testRefund(100, enums.RefundStatusPending, "", 0)}
+
+	tests := []struct {
+		name     string
+		refunded int64
+		refunds  []models.Refund
+		status   enums.PaymentStatus
+	}{
+		{"nothing refunded", 0, nil, enums.Approved},
+		{"partially refunded", 400, nil, enums.PartiallyRefunded},
+		{"fully refunded", 1000, nil, enums.Refunded},
+		{"refund pending", 400, pending, enums.RefundPending},
+		{"refund pending after nothing refunded", 0, pending, enums.RefundPending},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payment := models.Payment{
+				Amount:         money.New(1000, "USD"),
+				RefundedAmount: money.New(test.refunded, "USD"),
+			}
+			assert.Equal(t, test.status, refundedStatus(payment, test.refunds))
+		})
+	}
+}
+
+func TestRefundOutcome(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		status enums.PaymentStatus
+		want   enums.RefundStatus
+	}{
+		{enums.InProgress, enums.RefundStatusPending},
+		{enums.RefundPending, enums.RefundStatusPending},
+		{enums.Failed, enums.RefundStatusFailed},
+		{enums.Refunded, enums.RefundStatusSucceeded},
+		{enums.PartiallyRefunded, enums.RefundStatusSucceeded},
+		{enums.Approved, enums.RefundStatusSucceeded},
+	}
+	for _, tt := range tests {
+		got, err := refundOutcome(tt.status)
+		assert.Nil(err, tt.status)
+		assert.Equal(tt.want, got, tt.status)
+	}
+
+	for _, status := range []enums.PaymentStatus{enums.Cancelled, enums.ChargedBack, enums.Voided, enums.Unknown} {
+		_, err := refundOutcome(status)
+		assert.True(errors.Is(err, enums.InvalidStatus), status)
+	}
+}
+
+func TestCheckRefundBalance(t *testing.T) {
+	pending := []models.Refund{testRefund(300, enums.RefundStatusPending, "", 0)}
+	failed := []models.Refund{testRefund(300, enums.RefundStatusFailed, "", 0)}
+
+	tests := []struct {
+		name     string
+		refunded int64
+		refunds  []models.Refund
+		amount   int64
+		err      error
+	}{
+		{"full refund", 0, nil, 1000, nil},
+		{"over the captured amount", 0, nil, 1001, RefundExceedsBalance},
+		{"rest of a partial refund", 400, nil, 600, nil},
+		{"over the rest of a partial refund", 400, nil, 601, RefundExceedsBalance},
+		{"up to what pending refunds leave", 400, pending, 300, nil},
+		{"over what pending refunds leave", 400, pending, 301, RefundExceedsBalance},
+		{"failed refunds reserve nothing", 400, failed, 600, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payment := models.Payment{
+				Amount:         money.New(1000, "USD"),
+				RefundedAmount: money.New(test.refunded, "USD"),
+			}
+			err := checkRefundBalance(payment, test.refunds, money.New(test.amount, "USD"))
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
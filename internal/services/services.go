@@ -1,6 +1,15 @@
 package services
 
+import "payment-payments-api/pkg/umdw"
+
 type Services struct {
-	Payment *paymentService
-	User    *userService
+	Payment     *paymentService
+	User        *userService
+	Idempotency *idempotencyService
+	Card        *cardService
+	Ledger      *ledgerService
+	Merchant    *merchantService
+	ApiKey      *apiKeyService
+	Token       *tokenService
+	Cursor      *umdw.CursorSigner
 }
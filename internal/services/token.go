@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"log"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/auth"
+	"time"
+)
+
+var (
+	InvalidRefreshToken = errors.New("invalid refresh token")
+	RefreshTokenReused  = errors.New("refresh token reused")
+	InvalidChallenge    = errors.New("invalid login challenge")
+)
+
+// challengeTTL is how long a login challenge can be completed.
+const challengeTTL = 5 * time.Minute
+
+// LoginChallengeClaims identify a verified login challenge.
+type LoginChallengeClaims struct {
+	UserID    uuid.UUID
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+type TokenService interface {
+	IssueTokens(user *models.User) (dtoApi.TokenResponse, error)
+	Refresh(refreshToken string) (dtoApi.TokenResponse, error)
+	Logout(userID uuid.UUID, jti string, expiresAt time.Time, refreshToken string) error
+	IsRevoked(jti string) (bool, error)
+	RevokeUserSessions(userID uuid.UUID) error
+	IsSessionRevoked(userID uuid.UUID, expiresAt time.Time) (bool, error)
+	ParseAccessToken(token string) (jwt.MapClaims, error)
+	JWKS() auth.JWKS
+	IssueChallenge(user *models.User, purpose string) (dtoApi.LoginChallengeResponse, error)
+	ParseChallenge(token, purpose string) (LoginChallengeClaims, error)
+	RevokeChallenge(challenge LoginChallengeClaims) error
+	PurgeExpired() (int64, error)
+}
+
+type tokenService struct {
+	tokenRepository repositories.TokenRepository
+	userRepository  repositories.UserRepository
+	keys            *auth.KeySet
+	accessTTL       time.Duration
+	refreshTTL      time.Duration
+}
+
+func NewTokenService(tokenRepository repositories.TokenRepository, userRepository repositories.UserRepository,
+	keys *auth.KeySet, accessTTL, refreshTTL time.Duration) *tokenService {
+	return &tokenService{tokenRepository: tokenRepository,
+		userRepository: userRepository,
+		keys:           keys,
+		accessTTL:      accessTTL,
+		refreshTTL:     refreshTTL,
+	}
+}
+
+// IssueTokens starts a new refresh token family for user, as on login.
+func (s *tokenService) IssueTokens(user *models.User) (dtoApi.TokenResponse, error) {
+	return s.issue(user, uuid.New())
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// A token can only be used once: presenting it again means it leaked, so the
+// whole family is revoked and the legitimate holder has to log in again.
+func (s *tokenService) Refresh(refreshToken string) (dtoApi.TokenResponse, error) {
+	token, err := s.tokenRepository.GetRefreshTokenByHash(auth.HashRefreshToken(refreshToken))
+	if err != nil {
+		return dtoApi.TokenResponse{}, InvalidRefreshToken
+	}
+	now := time.Now()
+	if token.IsRevoked() || now.After(token.ExpiresAt) {
+		return dtoApi.TokenResponse{}, InvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		return dtoApi.TokenResponse{}, s.revokeFamily(token.FamilyID, RefreshTokenReused)
+	}
+
+	used, err := s.tokenRepository.UseRefreshToken(token.ID, now)
+	if err != nil {
+		return dtoApi.TokenResponse{}, err
+	}
+	if !used {
+		return dtoApi.TokenResponse{}, s.revokeFamily(token.FamilyID, RefreshTokenReused)
+	}
+
+	user, err := s.userRepository.FindByID(token.UserID)
+	if err != nil || !user.Enabled {
+		return dtoApi.TokenResponse{}, s.revokeFamily(token.FamilyID, InvalidRefreshToken)
+	}
+	return s.issue(user, token.FamilyID)
+}
+
+// Logout denies the access token jti until it expires and, when given,
+// revokes the family of the user's refreshToken.
+func (s *tokenService) Logout(userID uuid.UUID, jti string, expiresAt time.Time, refreshToken string) error {
+	err := s.tokenRepository.RevokeAccessToken(models.RevokedToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil || refreshToken == "" {
+		return err
+	}
+
+	token, err := s.tokenRepository.GetRefreshTokenByHash(auth.HashRefreshToken(refreshToken))
+	if err != nil || token.UserID != userID {
+		return InvalidRefreshToken
+	}
+	return s.tokenRepository.RevokeRefreshTokenFamily(token.FamilyID, time.Now())
+}
+
+func (s *tokenService) IsRevoked(jti string) (bool, error) {
+	return s.tokenRepository.IsAccessTokenRevoked(jti)
+}
+
+// RevokeUserSessions logs the user out everywhere: all their refresh token
+// families are revoked and the access tokens issued so far are denied.
+func (s *tokenService) RevokeUserSessions(userID uuid.UUID) error {
+	now := time.Now()
+	return s.tokenRepository.RevokeUserSessions(models.RevokedSession{
+		UserID:    userID,
+		RevokedAt: now,
+		ExpiresAt: now.Add(s.accessTTL),
+	})
+}
+
+// IsSessionRevoked reports whether the access token of userID expiring at
+// expiresAt was issued before the user's sessions were last revoked. Access
+// tokens carry their expiry in whole seconds, so one issued within the same
+// second as the revocation is denied too.
+func (s *tokenService) IsSessionRevoked(userID uuid.UUID, expiresAt time.Time) (bool, error) {
+	return s.tokenRepository.IsSessionRevoked(userID, expiresAt.Add(-s.accessTTL))
+}
+
+// ParseAccessToken verifies the signature and expiry of an access token and
+// returns its claims. Revocation is checked separately with IsRevoked.
+func (s *tokenService) ParseAccessToken(token string) (jwt.MapClaims, error) {
+	return s.keys.Parse(token)
+}
+
+// JWKS returns the public keys access tokens can be verified with.
+func (s *tokenService) JWKS() auth.JWKS {
+	return s.keys.JWKS()
+}
+
+// IssueChallenge signs a short-lived token standing for user's verified
+// password, to be completed as purpose says.
+func (s *tokenService) IssueChallenge(user *models.User, purpose string) (dtoApi.LoginChallengeResponse, error) {
+	claims := jwt.MapClaims{
+		"sub":                user.ID.String(),
+		auth.JwtClaimPurpose: purpose,
+		auth.JwtClaimTokenID: uuid.NewString(),
+	}
+	token, err := s.keys.Sign(claims, challengeTTL)
+	if err != nil {
+		return dtoApi.LoginChallengeResponse{}, err
+	}
+	return dtoApi.LoginChallengeResponse{
+		ChallengeToken: token,
+		Purpose:        purpose,
+		ExpiresAt:      time.Now().Add(challengeTTL),
+	}, nil
+}
+
+// ParseChallenge verifies a challenge token issued for purpose that was not
+// completed yet.
+func (s *tokenService) ParseChallenge(token, purpose string) (LoginChallengeClaims, error) {
+	claims, err := s.keys.Parse(token)
+	if err != nil || claims[auth.JwtClaimPurpose] != purpose {
+		return LoginChallengeClaims{}, InvalidChallenge
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return LoginChallengeClaims{}, InvalidChallenge
+	}
+	jti, _ := claims[auth.JwtClaimTokenID].(string)
+	if revoked, err := s.IsRevoked(jti); err != nil || revoked {
+		return LoginChallengeClaims{}, InvalidChallenge
+	}
+	exp, _ := claims["exp"].(float64)
+	return LoginChallengeClaims{
+		UserID:    userID,
+		TokenID:   jti,
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}, nil
+}
+
+// RevokeChallenge makes a completed challenge unusable.
+func (s *tokenService) RevokeChallenge(challenge LoginChallengeClaims) error {
+	return s.tokenRepository.RevokeAccessToken(models.RevokedToken{
+		JTI:       challenge.TokenID,
+		ExpiresAt: challenge.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// RotateKeysEvery reloads the signing keys on every interval until ctx is
+// done, and rotates them once the active key is older than maxAge. Of the
+// instances sharing the keys, the one taking the rotation lock rotates and
+// the others pick up its key on a later reload.
+func (s *tokenService) RotateKeysEvery(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.keys.Reload(); err != nil {
+				log.Printf("Error reloading signing keys: %v", err)
+				continue
+			}
+			if time.Since(s.keys.Active().CreatedAt) < maxAge {
+				continue
+			}
+			key, rotated, err := s.keys.RotateIfOlder(maxAge)
+			if errors.Is(err, auth.ErrRotationLocked) {
+				continue
+			}
+			if err != nil {
+				log.Printf("Error rotating signing keys: %v", err)
+				continue
+			}
+			if rotated {
+				log.Printf("Rotated signing keys, new key %s", key.ID)
+			}
+		}
+	}
+}
+
+func (s *tokenService) PurgeExpired() (int64, error) {
+	return s.tokenRepository.DeleteExpiredTokens(time.Now())
+}
+
+// PurgeExpiredEvery deletes expired tokens on every interval until ctx is done.
+func (s *tokenService) PurgeExpiredEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PurgeExpired()
+			if err != nil {
+				log.Printf("Error purging tokens: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Purged %d expired tokens", deleted)
+			}
+		}
+	}
+}
+
+// issue signs an access token for user and stores a new refresh token in
+// familyID.
+func (s *tokenService) issue(user *models.User, familyID uuid.UUID) (dtoApi.TokenResponse, error) {
+	claims, err := userClaims(user)
+	if err != nil {
+		return dtoApi.TokenResponse{}, err
+	}
+	now := time.Now()
+	claims[auth.JwtClaimTokenID] = uuid.NewString()
+	accessToken, err := s.keys.Sign(claims, s.accessTTL)
+	if err != nil {
+		return dtoApi.TokenResponse{}, err
+	}
+
+	refreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		return dtoApi.TokenResponse{}, err
+	}
+	stored, err := s.tokenRepository.CreateRefreshToken(models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		Hash:      auth.HashRefreshToken(refreshToken),
+		ExpiresAt: now.Add(s.refreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return dtoApi.TokenResponse{}, err
+	}
+
+	return dtoApi.TokenResponse{
+		User:                  dtoApi.MapUserToUserResponse(user),
+		Token:                 accessToken,
+		ExpiresAt:             now.Add(s.accessTTL),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// revokeFamily revokes every refresh token of familyID and returns cause, or
+// the database error if revoking failed.
+func (s *tokenService) revokeFamily(familyID uuid.UUID, cause error) error {
+	if err := s.tokenRepository.RevokeRefreshTokenFamily(familyID, time.Now()); err != nil {
+		return err
+	}
+	if errors.Is(cause, RefreshTokenReused) {
+		log.Printf("Refresh token family %s reused, revoked", familyID)
+	}
+	return cause
+}
+
+// userClaims turns user, without its password, into JWT claims.
+func userClaims(user *models.User) (jwt.MapClaims, error) {
+	payload := *user
+	payload.CleanSensitiveInfo()
+
+	var claims jwt.MapClaims
+	inBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(inBytes, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/auth"
+	"testing"
+	"time"
+)
+
+type fakeTokenRepository struct {
+	refresh  map[uuid.UUID]models.RefreshToken
+	revoked  map[string]models.RevokedToken
+	sessions map[uuid.UUID]models.RevokedSession
+	// loseUseRace makes UseRefreshToken report that a concurrent refresh
+	// used the token first.
+	loseUseRace bool
+}
+
+func newFakeTokenRepository() *fakeTokenRepository {
+	return &fakeTokenRepository{
+		refresh:  map[uuid.UUID]models.RefreshToken{},
+		revoked:  map[string]models.RevokedToken{},
+		sessions: map[uuid.UUID]models.RevokedSession{},
+	}
+}
+
+func (r *fakeTokenRepository) CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error) {
+	token.ID = uuid.New()
+	r.refresh[token.ID] = token
+	return token, nil
+}
+
+func (r *fakeTokenRepository) GetRefreshTokenByHash(hash string) (models.RefreshToken, error) {
+	for _, token := range r.refresh {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeTokenRepository) UseRefreshToken(id uuid.UUID, usedAt time.Time) (bool, error) {
+	token := r.refresh[id]
+	if r.loseUseRace || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	r.refresh[id] = token
+	return true, nil
+}
+
+func (r *fakeTokenRepository) RevokeRefreshTokenFamily(familyID uuid.UUID, revokedAt time.Time) error {
+	for id, token := range r.refresh {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			r.refresh[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *fakeTokenRepository) RevokeAccessToken(token models.RevokedToken) error {
+	r.revoked[token.JTI] = token
+	return nil
+}
+
+func (r *fakeTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+func (r *fakeTokenRepository) RevokeUserSessions(session models.RevokedSession) error {
+	for id, token := range r.refresh {
+		if token.UserID == session.UserID && token.RevokedAt == nil {
+			token.RevokedAt = &session.RevokedAt
+			r.refresh[id] = token
+		}
+	}
+	r.sessions[session.UserID] = session
+	return nil
+}
+
+func (r *fakeTokenRepository) IsSessionRevoked(userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	session, ok := r.sessions[userID]
+	return ok && !session.RevokedAt.Before(issuedAt), nil
+}
+
+func (r *fakeTokenRepository) DeleteExpiredTokens(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// familyRevoked reports whether every refresh token of familyID is revoked.
+func (r *fakeTokenRepository) familyRevoked(familyID uuid.UUID) bool {
+	for _, token := range r.refresh {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *fakeTokenRepository) byToken(refreshToken string) models.RefreshToken {
+	token, _ := r.GetRefreshTokenByHash(auth.HashRefreshToken(refreshToken))
+	return token
+}
+
+type fakeTokenUserRepository struct {
+	repositories.UserRepository
+	users map[uuid.UUID]*models.User
+}
+
+func (r *fakeTokenUserRepository) FindByID(id uuid.UUID) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func newTokenTestService(t *testing.T) (*tokenService, *fakeTokenRepository, *models.User) {
+	keys, err := auth.LoadKeySet(t.TempDir(), auth.AlgorithmES256, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{ID: uuid.New(), Email: "jane@example.com", Enabled: true}
+	tokens := newFakeTokenRepository()
+	users := &fakeTokenUserRepository{users: map[uuid.UUID]*models.User{user.ID: user}}
+	return NewTokenService(tokens, users, keys, time.Minute, time.Hour), tokens, user
+}
+
+func TestRefreshIssuesNextToken(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+
+	refreshed, err := s.Refresh(login.RefreshToken)
+
+	assert.Nil(err)
+	assert.NotEqual(login.RefreshToken, refreshed.RefreshToken)
+	assert.NotNil(tokens.byToken(login.RefreshToken).UsedAt)
+	assert.Equal(tokens.byToken(login.RefreshToken).FamilyID, tokens.byToken(refreshed.RefreshToken).FamilyID)
+	_, err = s.ParseAccessToken(refreshed.Token)
+	assert.Nil(err)
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+	refreshed, _ := s.Refresh(login.RefreshToken)
+
+	_, err := s.Refresh(login.RefreshToken)
+
+	assert.ErrorIs(err, RefreshTokenReused)
+	assert.True(tokens.familyRevoked(tokens.byToken(login.RefreshToken).FamilyID))
+	_, err = s.Refresh(refreshed.RefreshToken)
+	assert.ErrorIs(err, InvalidRefreshToken, "the successor is revoked too")
+}
+
+func TestRefreshLostRaceRevokesFamily(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+	tokens.loseUseRace = true
+
+	_, err := s.Refresh(login.RefreshToken)
+
+	assert.ErrorIs(err, RefreshTokenReused)
+	assert.True(tokens.familyRevoked(tokens.byToken(login.RefreshToken).FamilyID))
+	assert.Len(tokens.refresh, 1, "no successor is issued")
+}
+
+func TestRefreshDisabledUser(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+	user.Enabled = false
+
+	_, err := s.Refresh(login.RefreshToken)
+
+	assert.ErrorIs(err, InvalidRefreshToken)
+	assert.True(tokens.familyRevoked(tokens.byToken(login.RefreshToken).FamilyID))
+}
+
+func TestRefreshExpiredToken(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+	token := tokens.byToken(login.RefreshToken)
+	token.ExpiresAt = time.Now().Add(-time.Second)
+	tokens.refresh[token.ID] = token
+
+	_, err := s.Refresh(login.RefreshToken)
+
+	assert.ErrorIs(err, InvalidRefreshToken)
+	assert.Nil(tokens.byToken(login.RefreshToken).UsedAt)
+}
+
+func TestRefreshUnknownToken(t *testing.T) {
+	assert := assert.New(t)
+	s, _, _ := newTokenTestService(t)
+
+	_, err := s.Refresh("unknown")
+
+	assert.ErrorIs(err, InvalidRefreshToken)
+}
+
+func TestLogoutRevokesFamily(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+
+	err := s.Logout(user.ID, "jti-1", time.Now().Add(time.Minute), login.RefreshToken)
+
+	assert.Nil(err)
+	revoked, _ := s.IsRevoked("jti-1")
+	assert.True(revoked)
+	assert.True(tokens.familyRevoked(tokens.byToken(login.RefreshToken).FamilyID))
+}
+
+func TestLogoutWithAnotherUsersRefreshToken(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+
+	err := s.Logout(uuid.New(), "jti-1", time.Now().Add(time.Minute), login.RefreshToken)
+
+	assert.ErrorIs(err, InvalidRefreshToken)
+	assert.False(tokens.familyRevoked(tokens.byToken(login.RefreshToken).FamilyID))
+	revoked, _ := s.IsRevoked("jti-1")
+	assert.True(revoked, "the caller's own access token is still revoked")
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	assert := assert.New(t)
+	s, tokens, user := newTokenTestService(t)
+	login, _ := s.IssueTokens(user)
+
+	assert.Nil(s.RevokeUserSessions(user.ID))
+
+	assert.True(tokens.familyRevoked(tokens.byToken(login.RefreshToken).FamilyID))
+	revoked, _ := s.IsSessionRevoked(user.ID, login.ExpiresAt)
+	assert.True(revoked, "access tokens issued before are denied")
+	revoked, _ = s.IsSessionRevoked(user.ID, time.Now().Add(s.accessTTL+2*time.Second))
+	assert.False(revoked, "access tokens issued after are accepted")
+	revoked, _ = s.IsSessionRevoked(uuid.New(), login.ExpiresAt)
+	assert.False(revoked)
+}
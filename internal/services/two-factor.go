@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/pkg/auth"
+	"time"
+)
+
+var (
+	TwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	TwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	TwoFactorRequired       = errors.New("two-factor authentication is required for this role")
+	InvalidTwoFactorCode    = errors.New("invalid two-factor code")
+)
+
+const (
+	totpIssuer        = "Payments API"
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+// Login challenge purposes, see dto.LoginChallengeResponse.
+const (
+	ChallengeTwoFactor  = "2fa"
+	ChallengeEnrollment = "2fa-enroll"
+)
+
+// LoginChallenge returns the challenge user must pass before getting tokens:
+// a TOTP code when enrolled, enrollment when the role requires 2FA, or none.
+func (s *userService) LoginChallenge(user *models.User) string {
+	switch {
+	case user.TOTPEnabled:
+		return ChallengeTwoFactor
+	case s.RequiresTwoFactor(user.Role):
+		return ChallengeEnrollment
+	}
+	return ""
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user. It only takes
+// effect once confirmed with a code from it.
+func (s *userService) EnrollTwoFactor(id uuid.UUID) (dtoApi.TwoFactorEnrollmentResponse, error) {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return dtoApi.TwoFactorEnrollmentResponse{}, err
+	}
+	if user.TOTPEnabled {
+		return dtoApi.TwoFactorEnrollmentResponse{}, TwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return dtoApi.TwoFactorEnrollmentResponse{}, err
+	}
+	if user.TOTPSecret, err = s.vaultCipher.Seal([]byte(secret), user.ID[:]); err != nil {
+		return dtoApi.TwoFactorEnrollmentResponse{}, err
+	}
+	user.TOTPLastStep = 0
+	user.UpdatedAt = time.Now()
+	if err = s.repo.UpdateUser(user, "TOTPSecret", "TOTPLastStep", "UpdatedAt"); err != nil {
+		return dtoApi.TwoFactorEnrollmentResponse{}, err
+	}
+
+	return dtoApi.TwoFactorEnrollmentResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables 2FA once code matches the enrolled secret and
+// returns fresh recovery codes.
+func (s *userService) ConfirmTwoFactor(id uuid.UUID, code string) (dtoApi.RecoveryCodesResponse, error) {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return dtoApi.RecoveryCodesResponse{}, err
+	}
+	if user.TOTPEnabled {
+		return dtoApi.RecoveryCodesResponse{}, TwoFactorAlreadyEnabled
+	}
+	if len(user.TOTPSecret) == 0 {
+		return dtoApi.RecoveryCodesResponse{}, TwoFactorNotEnabled
+	}
+	if ok, err := s.checkTOTP(user, code); err != nil || !ok {
+		return dtoApi.RecoveryCodesResponse{}, InvalidTwoFactorCode
+	}
+
+	codes, err := s.newRecoveryCodes(user.ID)
+	if err != nil {
+		return dtoApi.RecoveryCodesResponse{}, err
+	}
+	if err = s.setTOTPEnabled(user, true); err != nil {
+		return dtoApi.RecoveryCodesResponse{}, err
+	}
+	s.audit(enums.AuditTwoFactorEnabled, user.Email, user.ID.String(), "", "")
+	return dtoApi.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns 2FA off. Users must prove possession with a code
+// and cannot opt out when their role requires 2FA; admins resetting a lost
+// device pass force.
+func (s *userService) DisableTwoFactor(id uuid.UUID, code string, force bool, actorID string) error {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return TwoFactorNotEnabled
+	}
+	if !force {
+		if s.RequiresTwoFactor(user.Role) {
+			return TwoFactorRequired
+		}
+		if ok, err := s.checkSecondFactor(user, code); err != nil || !ok {
+			return InvalidTwoFactorCode
+		}
+	}
+
+	user.TOTPSecret = nil
+	if err = s.setTOTPEnabled(user, false); err != nil {
+		return err
+	}
+	if err = s.repo.DeleteRecoveryCodes(user.ID); err != nil {
+		return err
+	}
+	s.audit(enums.AuditTwoFactorDisabled, user.Email, actorID, "", "")
+	return nil
+}
+
+// VerifyTwoFactor completes a login challenge with a TOTP or recovery code.
+// Wrong codes count as failed logins, so guessing is throttled like
+// passwords.
+func (s *userService) VerifyTwoFactor(id uuid.UUID, code, ip string) (*models.User, error) {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled || !user.Enabled {
+		return nil, InvalidTwoFactorCode
+	}
+
+	now := time.Now()
+	emailKey := loginEmailKey(user.Email)
+	if err = s.checkLoginThrottle(emailKey, now); err != nil {
+		return nil, err
+	}
+	if ok, err := s.checkSecondFactor(user, code); err != nil || !ok {
+		s.recordLoginFailure(emailKey, s.loginPolicy.MaxFailures, ip, now)
+		return nil, InvalidTwoFactorCode
+	}
+
+	if err = s.repo.DeleteLoginThrottle(emailKey); err != nil {
+		log.Printf("Error clearing login failures of %s: %v", emailKey, err)
+	}
+	return user, nil
+}
+
+// RequiresTwoFactor reports whether the policy of role requires 2FA.
+func (s *userService) RequiresTwoFactor(role enums.Role) bool {
+	policy, err := s.repo.GetRolePolicy(role)
+	return err == nil && policy.RequireTwoFactor
+}
+
+// ListRolePolicies returns the policy of every role, defaults included.
+func (s *userService) ListRolePolicies() ([]models.RolePolicy, error) {
+	stored, err := s.repo.GetRolePolicies()
+	if err != nil {
+		return nil, err
+	}
+	byRole := make(map[enums.Role]models.RolePolicy, len(stored))
+	for _, policy := range stored {
+		byRole[policy.Role] = policy
+	}
+
+	policies := make([]models.RolePolicy, len(enums.Roles))
+	for i, role := range enums.Roles {
+		policy, ok := byRole[role]
+		if !ok {
+			policy = models.RolePolicy{Role: role}
+		}
+		policies[i] = policy
+	}
+	return policies, nil
+}
+
+func (s *userService) SetRolePolicy(role string, request dtoApi.RolePolicyRequest, actorID string) (models.RolePolicy, error) {
+	parsed, err := enums.ParseRole(role)
+	if err != nil {
+		return models.RolePolicy{}, fmt.Errorf("%w: %v", InvalidUser, err)
+	}
+
+	policy := models.RolePolicy{
+		Role:             parsed,
+		RequireTwoFactor: request.RequireTwoFactor,
+		UpdatedAt:        time.Now(),
+	}
+	if err = s.repo.SaveRolePolicy(&policy); err != nil {
+		return models.RolePolicy{}, err
+	}
+	s.audit(enums.AuditRolePolicyUpdated, role, actorID, "",
+		fmt.Sprintf("requireTwoFactor=%t", policy.RequireTwoFactor))
+	return policy, nil
+}
+
+// checkSecondFactor accepts a TOTP code or, failing that, an unused
+// recovery code, which is then used up.
+func (s *userService) checkSecondFactor(user *models.User, code string) (bool, error) {
+	if ok, err := s.checkTOTP(user, code); err != nil || ok {
+		return ok, err
+	}
+
+	used, err := s.repo.UseRecoveryCode(user.ID, auth.HashRecoveryCode(code), time.Now())
+	if err != nil || !used {
+		return false, err
+	}
+	s.audit(enums.AuditRecoveryCodeUsed, user.Email, user.ID.String(), "", "")
+	return true, nil
+}
+
+// checkTOTP verifies code against the user's secret and records the step so
+// the same code cannot be used twice.
+func (s *userService) checkTOTP(user *models.User, code string) (bool, error) {
+	secret, err := s.vaultCipher.Open(user.TOTPSecret, user.ID[:])
+	if err != nil {
+		return false, err
+	}
+	step, ok := auth.VerifyTOTP(string(secret), code, time.Now(), totpSkew, user.TOTPLastStep)
+	if !ok {
+		return false, nil
+	}
+	return s.repo.UseTOTPStep(user.ID, step)
+}
+
+func (s *userService) setTOTPEnabled(user *models.User, enabled bool) error {
+	user.TOTPEnabled = enabled
+	user.UpdatedAt = time.Now()
+	return s.repo.UpdateUser(user, "TOTPSecret", "TOTPEnabled", "UpdatedAt")
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns the new
+// ones in plain text.
+func (s *userService) newRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stored := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		stored[i] = models.RecoveryCode{
+			UserID:    userID,
+			Hash:      auth.HashRecoveryCode(code),
+			CreatedAt: now,
+		}
+	}
+	if err = s.repo.ReplaceRecoveryCodes(userID, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
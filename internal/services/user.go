@@ -1,37 +1,273 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"log"
+	dtoApi "payment-payments-api/internal/api/dto"
 	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/repositories"
+	"payment-payments-api/pkg/auth"
+	"payment-payments-api/pkg/mail"
+	"payment-payments-api/pkg/umdw"
+	"payment-payments-api/pkg/vault"
+	"strings"
+	"time"
 )
 
-func NewUserService(r repositories.UserRepository) *userService {
+var (
+	UserNotFound       = errors.New("user not found")
+	UserEmailTaken     = errors.New("user email already exists")
+	InvalidUser        = errors.New("invalid user")
+	InvalidCredentials = errors.New("invalid credentials")
+)
+
+const (
+	minPasswordLength = 8
+	maxUserListLimit  = 100
+)
+
+// userSortColumns whitelists the fields users can be sorted by.
+var userSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"email":     "email",
+	"lastName":  "last_name",
+}
+
+func NewUserService(r repositories.UserRepository, merchantService MerchantService, tokenService TokenService,
+	vaultCipher *vault.Cipher, mailer mail.Mailer, loginPolicy LoginPolicy,
+	accountEmails AccountEmails) *userService {
 	return &userService{
-		repo: r,
+		repo:            r,
+		merchantService: merchantService,
+		tokenService:    tokenService,
+		vaultCipher:     vaultCipher,
+		mailer:          mailer,
+		loginPolicy:     loginPolicy,
+		accountEmails:   accountEmails,
 	}
 }
 
 type userService struct {
-	repo repositories.UserRepository
+	repo            repositories.UserRepository
+	merchantService MerchantService
+	tokenService    TokenService
+	vaultCipher     *vault.Cipher
+	mailer          mail.Mailer
+	loginPolicy     LoginPolicy
+	accountEmails   AccountEmails
 }
 
-func (s *userService) CreateUser(firstName, lastName, email string) (*models.User, error) {
-	user, err := models.NewUser(firstName, lastName, email)
+// CreateUser creates a user. Without a password the user is invited to
+// choose one by email; otherwise they are asked to verify their email.
+func (s *userService) CreateUser(request dtoApi.CreateUserRequest) (*models.User, error) {
+	user, err := s.createUser(request, false)
+	if err != nil {
+		return nil, err
+	}
+
+	purpose := enums.UserTokenPurpose(enums.UserTokenEmailVerification)
+	if request.Password == "" {
+		purpose = enums.UserTokenInvite
+	}
+	if err = s.sendAccountEmail(user, purpose); err != nil {
+		log.Printf("Error sending %s email to user %s: %v", purpose, user.ID, err)
+	}
+	return user, nil
+}
+
+func (s *userService) createUser(request dtoApi.CreateUserRequest, verified bool) (*models.User, error) {
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if existing, err := s.repo.GetPaymentByEmail(email); err == nil && existing != nil {
+		return nil, UserEmailTaken
+	}
+	if request.Password != "" {
+		if err := checkPassword(request.Password); err != nil {
+			return nil, err
+		}
+	}
+	role, err := s.checkRole(request.Role, request.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := models.NewUser(request.FirstName, request.LastName, email, request.Password, role)
 	if err != nil {
 		return nil, err
 	}
+	user.MerchantID = request.MerchantID
+	if verified {
+		s.markEmailVerified(user)
+	}
 	return s.repo.CreateUser(user)
 }
 
 func (s *userService) GetUserById(id uuid.UUID) (*models.User, error) {
-	return s.repo.FindByID(id)
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, UserNotFound
+	}
+	return user, nil
 }
 
 func (s *userService) GetUserByEmail(email string) (*models.User, error) {
-	return s.repo.GetPaymentByEmail(email)
+	return s.repo.GetPaymentByEmail(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func (s *userService) ListUsers(list umdw.List) ([]dtoApi.UserResponse, int64, error) {
+	if list.Limit > maxUserListLimit {
+		return nil, 0, fmt.Errorf("%w: limit must be at most %d", InvalidUser, maxUserListLimit)
+	}
+	if list.By == "" {
+		list.By = "createdAt"
+	}
+	column, ok := userSortColumns[list.By]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: cannot sort by %q", InvalidUser, list.By)
+	}
+	list.By = column
+
+	users, total, err := s.repo.ListUsers(list)
+	if err != nil {
+		return nil, 0, err
+	}
+	dtos := make([]dtoApi.UserResponse, len(users))
+	for i := range users {
+		dtos[i] = dtoApi.MapUserToUserResponse(&users[i])
+	}
+	return dtos, total, nil
+}
+
+// UpdateUser changes the profile and role of a user. A new role or merchant
+// logs the user out of every session, so tokens never outlive the grant.
+func (s *userService) UpdateUser(id uuid.UUID, request dtoApi.UpdateUserRequest) (*models.User, error) {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	role, merchantID := user.Role, user.MerchantID
+	if request.Role != "" {
+		if user.Role, err = s.checkRole(request.Role, request.MerchantID); err != nil {
+			return nil, err
+		}
+		user.MerchantID = request.MerchantID
+	}
+	if request.FirstName != "" {
+		user.FirstName = request.FirstName
+	}
+	if request.LastName != "" {
+		user.LastName = request.LastName
+	}
+	user.UpdatedAt = time.Now()
+	if err = s.repo.UpdateUser(user, "Role", "MerchantID", "FirstName", "LastName", "UpdatedAt"); err != nil {
+		return nil, err
+	}
+	if user.Role != role || user.MerchantID != merchantID {
+		return user, s.tokenService.RevokeUserSessions(user.ID)
+	}
+	return user, nil
+}
+
+// SetUserEnabled enables or disables a user. Disabling logs the user out of
+// every session.
+func (s *userService) SetUserEnabled(id uuid.UUID, enabled bool) (*models.User, error) {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	user.Enabled = enabled
+	user.UpdatedAt = time.Now()
+	if err = s.repo.UpdateUser(user, "Enabled", "UpdatedAt"); err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return user, s.tokenService.RevokeUserSessions(user.ID)
+	}
+	return user, nil
+}
+
+// ChangePassword sets a new password and logs the user out of every session.
+// The current password is required unless requireCurrent is false, which is
+// reserved for admins.
+func (s *userService) ChangePassword(id uuid.UUID, request dtoApi.ChangePasswordRequest, requireCurrent bool) error {
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if requireCurrent && !auth.VerifyPassword(request.CurrentPassword, user.PasswordSalt, user.Password) {
+		return InvalidCredentials
+	}
+	if err = checkPassword(request.NewPassword); err != nil {
+		return err
+	}
+	if err = user.SetPassword(request.NewPassword); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	if err = s.repo.UpdateUser(user, "Password", "PasswordSalt", "UpdatedAt"); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserSessions(user.ID)
+}
+
+// RehashPassword upgrades the stored hash of user when it uses a legacy
+// algorithm or outdated parameters. password must already be verified.
+func (s *userService) RehashPassword(user *models.User, password string) error {
+	if !auth.NeedsRehash(user.Password) {
+		return nil
+	}
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	return s.repo.UpdateUser(user, "Password", "PasswordSalt")
 }
 
-func (s *userService) UpdateUser(user *models.User) error {
-	return s.repo.UpdateUser(user)
+// BootstrapAdmin creates the configured admin on first start. An existing
+// user with the same email is left untouched.
+func (s *userService) BootstrapAdmin(email, password string) error {
+	if email == "" || password == "" {
+		return nil
+	}
+	if existing, err := s.GetUserByEmail(email); err == nil && existing != nil {
+		return nil
+	}
+	_, err := s.createUser(dtoApi.CreateUserRequest{
+		FirstName: "Admin",
+		Email:     email,
+		Password:  password,
+		Role:      enums.RoleAdmin,
+	}, true)
+	if err != nil {
+		return err
+	}
+	log.Printf("Created bootstrap admin %s", email)
+	return nil
+}
+
+// checkRole parses role, defaulting to customer, and makes sure merchant
+// operators belong to an existing merchant.
+func (s *userService) checkRole(role, merchantID string) (enums.Role, error) {
+	if role == "" {
+		role = enums.RoleCustomer
+	}
+	parsed, err := enums.ParseRole(role)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidUser, err)
+	}
+	if parsed != enums.RoleMerchantOperator {
+		if merchantID != "" {
+			return "", fmt.Errorf("%w: only merchant operators belong to a merchant", InvalidUser)
+		}
+		return parsed, nil
+	}
+	id, err := uuid.Parse(merchantID)
+	if err != nil {
+		return "", fmt.Errorf("%w: merchant operators need a merchantId", InvalidUser)
+	}
+	if _, err = s.merchantService.GetMerchant(id); err != nil {
+		return "", err
+	}
+	return parsed, nil
 }
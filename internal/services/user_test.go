@@ -0,0 +1,95 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	dtoApi "payment-payments-api/internal/api/dto"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
+	"payment-payments-api/internal/repositories"
+	"testing"
+)
+
+// fakeUserRepository keeps users in memory. Methods the tests do not use
+// are left to the embedded nil interface.
+type fakeUserRepository struct {
+	repositories.UserRepository
+	users map[uuid.UUID]*models.User
+}
+
+func (r *fakeUserRepository) FindByID(id uuid.UUID) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) UpdateUser(user *models.User, fields ...string) error {
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+// fakeSessionRevoker records whose sessions were revoked.
+type fakeSessionRevoker struct {
+	TokenService
+	revoked []uuid.UUID
+}
+
+func (t *fakeSessionRevoker) RevokeUserSessions(userID uuid.UUID) error {
+	t.revoked = append(t.revoked, userID)
+	return nil
+}
+
+func newUserTestService(user models.User) (*userService, *fakeSessionRevoker) {
+	repo := &fakeUserRepository{users: map[uuid.UUID]*models.User{user.ID: &user}}
+	tokens := &fakeSessionRevoker{}
+	return NewUserService(repo, nil, tokens, nil, nil, testLoginPolicy, AccountEmails{}), tokens
+}
+
+func TestUpdateUserRevokesSessionsOnRoleChange(t *testing.T) {
+	assert := assert.New(t)
+	user := models.User{ID: uuid.New(), Role: enums.RoleAdmin, Enabled: true}
+
+	tests := []struct {
+		name    string
+		request dtoApi.UpdateUserRequest
+		revoked bool
+	}{
+		{"new role", dtoApi.UpdateUserRequest{Role: enums.RoleSupportReadOnly}, true},
+		{"same role", dtoApi.UpdateUserRequest{Role: enums.RoleAdmin}, false},
+		{"profile only", dtoApi.UpdateUserRequest{FirstName: "Ada"}, false},
+	}
+	for _, test := range tests {
+		s, tokens := newUserTestService(user)
+
+		updated, err := s.UpdateUser(user.ID, test.request)
+
+		assert.Nil(err, test.name)
+		assert.NotNil(updated, test.name)
+		if test.revoked {
+			assert.Equal([]uuid.UUID{user.ID}, tokens.revoked, test.name)
+		} else {
+			assert.Empty(tokens.revoked, test.name)
+		}
+	}
+}
+
+func TestSetUserEnabledRevokesSessionsWhenDisabled(t *testing.T) {
+	assert := assert.New(t)
+	user := models.User{ID: uuid.New(), Role: enums.RoleCustomer, Enabled: true}
+
+	s, tokens := newUserTestService(user)
+	updated, err := s.SetUserEnabled(user.ID, false)
+	assert.Nil(err)
+	assert.False(updated.Enabled)
+	assert.Equal([]uuid.UUID{user.ID}, tokens.revoked)
+
+	s, tokens = newUserTestService(user)
+	_, err = s.SetUserEnabled(user.ID, true)
+	assert.Nil(err)
+	assert.Empty(tokens.revoked)
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const ApiKeyHeader = "X-API-Key"
+
+const (
+	apiKeyScheme       = "mk"
+	apiKeyPrefixBytes  = 4
+	apiKeySecretBytes  = 24
+	apiKeyPartsDivider = "_"
+)
+
+// NewApiKey returns a random key of the form mk_<prefix>_<secret> and its
+// prefix. Only the prefix and the hash of the key should be stored.
+func NewApiKey() (key string, prefix string, err error) {
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	if _, err = rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, apiKeySecretBytes)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+
+	prefix = apiKeyScheme + apiKeyPartsDivider + hex.EncodeToString(prefixBytes)
+	key = prefix + apiKeyPartsDivider + hex.EncodeToString(secretBytes)
+	return key, prefix, nil
+}
+
+// ApiKeyPrefix returns the identifying prefix of key, and false when key is
+// not shaped like a key made by NewApiKey.
+func ApiKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, apiKeyPartsDivider)
+	if len(parts) != 3 || parts[0] != apiKeyScheme ||
+		len(parts[1]) != 2*apiKeyPrefixBytes || len(parts[2]) != 2*apiKeySecretBytes {
+		return "", false
+	}
+	return parts[0] + apiKeyPartsDivider + parts[1], true
+}
+
+// HashApiKey returns the hex SHA-256 of key. Keys carry enough entropy that a
+// fast unsalted hash is safe, unlike passwords.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyApiKey reports in constant time whether key hashes to hash.
+func VerifyApiKey(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashApiKey(key)), []byte(hash)) == 1
+}
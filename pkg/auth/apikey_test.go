@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewApiKey(t *testing.T) {
+	assert := assert.New(t)
+
+	key, prefix, err := NewApiKey()
+
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(key, prefix+"_"))
+
+	parsed, ok := ApiKeyPrefix(key)
+	assert.True(ok)
+	assert.Equal(prefix, parsed)
+}
+
+func TestApiKeyPrefixInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := ApiKeyPrefix("mk_abc")
+	assert.False(ok)
+
+	_, ok = ApiKeyPrefix("xx_01234567_" + strings.Repeat("a", 48))
+	assert.False(ok)
+}
+
+func TestVerifyApiKey(t *testing.T) {
+	assert := assert.New(t)
+
+	key, _, _ := NewApiKey()
+	other, _, _ := NewApiKey()
+	hash := HashApiKey(key)
+
+	assert.True(VerifyApiKey(key, hash))
+	assert.False(VerifyApiKey(other, hash))
+}
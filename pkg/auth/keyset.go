@@ -0,0 +1,497 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+const (
+	keyFileExt = ".pem"
+	rsaKeyBits = 2048
+
+	// keyCreatedHeader is the PEM header holding a key's creation time.
+	keyCreatedHeader = "Created"
+	kidTimeLayout    = "20060102T150405Z"
+
+	// lockFileName is created in the key directory by the instance rotating
+	// keys. A lock older than lockStaleAfter was left by an instance that
+	// died mid-rotation and is broken.
+	lockFileName   = "rotate.lock"
+	lockStaleAfter = time.Minute
+	lockRetryDelay = 100 * time.Millisecond
+)
+
+var (
+	ErrUnknownKey         = errors.New("unknown signing key")
+	ErrUnsupportedKey     = errors.New("unsupported signing key")
+	ErrUnsupportedAlg     = errors.New("unsupported signing algorithm")
+	ErrNoSigningKey       = errors.New("no signing key")
+	ErrSigningKeyMismatch = errors.New("token algorithm does not match its key")
+	ErrRotationLocked     = errors.New("keys are being rotated by another instance")
+)
+
+// SigningKey is a private key of a KeySet, identified by the kid header of
+// the tokens it signs.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	CreatedAt time.Time
+}
+
+// KeySet signs JWTs with its newest key and verifies them with any key it
+// holds, so tokens signed before a rotation stay valid while the previous
+// keys are retained. Keys are PKCS#8 PEM files named <kid>.pem in dir, with
+// their creation time in a PEM header. Instances sharing dir take turns to
+// rotate through a lock file in it.
+type KeySet struct {
+	dir       string
+	algorithm string
+	retained  int
+	keepFor   time.Duration
+
+	mu     sync.RWMutex
+	keys   map[string]SigningKey
+	active SigningKey
+}
+
+// LoadKeySet reads the keys in dir, creating the directory and a first key
+// using algorithm when there are none. Keys beyond the newest retained ones
+// are only deleted once older than keepFor, which should cover the rotation
+// interval plus the lifetime of the tokens they sign.
+func LoadKeySet(dir, algorithm string, retained int, keepFor time.Duration) (*KeySet, error) {
+	if _, err := signingMethod(algorithm); err != nil {
+		return nil, err
+	}
+	if retained < 1 {
+		retained = 1
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{dir: dir, algorithm: algorithm, retained: retained, keepFor: keepFor}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	for ks.Active().ID == "" {
+		// Another instance starting at the same time may be creating the
+		// first key; wait for it rather than create a second one.
+		_, _, err := ks.RotateIfOlder(0)
+		if errors.Is(err, ErrRotationLocked) {
+			time.Sleep(lockRetryDelay)
+			if err = ks.Reload(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Reload reads the key files again, picking up keys rotated by another
+// instance sharing dir.
+func (ks *KeySet) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*"+keyFileExt))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]SigningKey, len(paths))
+	for _, path := range paths {
+		key, err := readSigningKey(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		keys[key.ID] = key
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	ks.active = newestKey(keys)
+	return nil
+}
+
+// Rotate generates a new key, makes it the signing key and drops the old
+// keys beyond the retained count. It fails with ErrRotationLocked while
+// another instance is rotating.
+func (ks *KeySet) Rotate() (SigningKey, error) {
+	unlock, err := ks.lock()
+	if err != nil {
+		return SigningKey{}, err
+	}
+	defer unlock()
+	return ks.rotate()
+}
+
+// RotateIfOlder rotates the keys when the active key is older than maxAge,
+// or there is none, and reports whether it did. The keys are read again once
+// the lock is taken, so of several instances due to rotate only the first
+// one does.
+func (ks *KeySet) RotateIfOlder(maxAge time.Duration) (SigningKey, bool, error) {
+	unlock, err := ks.lock()
+	if err != nil {
+		return SigningKey{}, false, err
+	}
+	defer unlock()
+
+	if err = ks.Reload(); err != nil {
+		return SigningKey{}, false, err
+	}
+	if active := ks.Active(); active.ID != "" && time.Since(active.CreatedAt) < maxAge {
+		return active, false, nil
+	}
+	key, err := ks.rotate()
+	return key, err == nil, err
+}
+
+func (ks *KeySet) rotate() (SigningKey, error) {
+	key, err := generateSigningKey(ks.algorithm)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	if err = writeSigningKey(filepath.Join(ks.dir, key.ID+keyFileExt), key); err != nil {
+		return SigningKey{}, err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys == nil {
+		ks.keys = map[string]SigningKey{}
+	}
+	ks.keys[key.ID] = key
+	ks.active = key
+	ks.prune()
+	return key, nil
+}
+
+// Active returns the key new tokens are signed with.
+func (ks *KeySet) Active() SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Sign signs claims with the active key, setting exp to duration from now.
+func (ks *KeySet) Sign(claims jwt.MapClaims, duration time.Duration) (string, error) {
+	key := ks.Active()
+	if key.ID == "" {
+		return "", ErrNoSigningKey
+	}
+
+	claims["exp"] = time.Now().Add(duration).Unix()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Parse verifies jwtToken against the key named by its kid header. Tokens
+// without a kid predate the key set and are checked against the legacy
+// HS512 secret when one is configured.
+func (ks *KeySet) Parse(jwtToken string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(jwtToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return legacyKey(token)
+		}
+
+		ks.mu.RLock()
+		key, ok := ks.keys[kid]
+		ks.mu.RUnlock()
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrSigningKeyMismatch
+		}
+		return key.Private.Public(), nil
+	})
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorUnverifiable != 0 && validationErr.Inner != nil {
+		return nil, validationErr.Inner
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("jwt.MapClaims parse error")
+	}
+	return claims, nil
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key, newest first.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	keys := sortedKeys(ks.keys)
+	ks.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// private functions
+
+// prune drops the keys beyond the retained count that are older than
+// keepFor, deleting their files. Younger ones may still have signed tokens
+// that have not expired. The caller holds ks.mu.
+func (ks *KeySet) prune() {
+	keys := sortedKeys(ks.keys)
+	for _, key := range keys[min(len(keys), ks.retained):] {
+		if time.Since(key.CreatedAt) < ks.keepFor {
+			continue
+		}
+		delete(ks.keys, key.ID)
+		_ = os.Remove(filepath.Join(ks.dir, key.ID+keyFileExt))
+	}
+}
+
+// lock creates the lock file of dir and returns the function removing it.
+func (ks *KeySet) lock() (func(), error) {
+	path := filepath.Join(ks.dir, lockFileName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		info, statErr := os.Stat(path)
+		if statErr != nil || time.Since(info.ModTime()) < lockStaleAfter {
+			return nil, ErrRotationLocked
+		}
+		if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrRotationLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = file.Close(); err != nil {
+		return nil, err
+	}
+	return func() { _ = os.Remove(path) }, nil
+}
+
+func legacyKey(token *jwt.Token) (interface{}, error) {
+	secret := os.Getenv(jwtEnvKey)
+	if secret == "" || token.Method != signMethod {
+		return nil, ErrUnknownKey
+	}
+	return []byte(secret), nil
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmES256:
+		return jwt.SigningMethodES256, nil
+	}
+	return nil, ErrUnsupportedAlg
+}
+
+func generateSigningKey(algorithm string) (SigningKey, error) {
+	method, err := signingMethod(algorithm)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	var private crypto.Signer
+	if algorithm == AlgorithmRS256 {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	} else {
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	now := time.Now().UTC()
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return SigningKey{}, err
+	}
+	return SigningKey{
+		ID:        fmt.Sprintf("%s-%x", now.Format(kidTimeLayout), suffix),
+		Method:    method,
+		Private:   private,
+		CreatedAt: now,
+	}, nil
+}
+
+// writeSigningKey writes key to path atomically: the PEM goes to a synced
+// temporary file in the same directory, which is then renamed over path, so
+// other instances never load a partially written key.
+func writeSigningKey(path string, key SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{keyCreatedHeader: key.CreatedAt.UTC().Format(time.RFC3339Nano)},
+		Bytes:   der,
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(pem.EncodeToMemory(block)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readSigningKey parses a PEM private key. The algorithm follows from the
+// key type: RSA keys sign RS256 and P-256 keys ES256.
+func readSigningKey(path string) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, ErrUnsupportedKey
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	key := SigningKey{ID: strings.TrimSuffix(filepath.Base(path), keyFileExt)}
+	if key.CreatedAt, err = keyCreatedAt(path, key.ID, block); err != nil {
+		return SigningKey{}, err
+	}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private = jwt.SigningMethodRS256, private
+	case *ecdsa.PrivateKey:
+		if private.Curve != elliptic.P256() {
+			return SigningKey{}, ErrUnsupportedKey
+		}
+		key.Method, key.Private = jwt.SigningMethodES256, private
+	default:
+		return SigningKey{}, ErrUnsupportedKey
+	}
+	return key, nil
+}
+
+// keyCreatedAt reads the creation time of a key from its PEM header, or from
+// its kid for keys written before the header. The file's modification time,
+// which copying the file changes, is only used for keys added by hand.
+func keyCreatedAt(path, kid string, block *pem.Block) (time.Time, error) {
+	if created, ok := block.Headers[keyCreatedHeader]; ok {
+		return time.Parse(time.RFC3339Nano, created)
+	}
+	if prefix, _, ok := strings.Cut(kid, "-"); ok {
+		if created, err := time.Parse(kidTimeLayout, prefix); err == nil {
+			return created, nil
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// sortedKeys orders keys newest first, breaking ties by kid.
+func sortedKeys(keys map[string]SigningKey) []SigningKey {
+	sorted := make([]SigningKey, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+	return sorted
+}
+
+func newestKey(keys map[string]SigningKey) SigningKey {
+	sorted := sortedKeys(keys)
+	if len(sorted) == 0 {
+		return SigningKey{}
+	}
+	return sorted[0]
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadKeySetCreatesKey(t *testing.T) {
+	assert := assert.New(t)
+	dir := filepath.Join(t.TempDir(), "keys")
+
+	ks, err := LoadKeySet(dir, AlgorithmES256, 2, 0)
+
+	assert.Nil(err)
+	assert.NotEmpty(ks.Active().ID)
+	assert.FileExists(filepath.Join(dir, ks.Active().ID+keyFileExt))
+
+	reloaded, err := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	assert.Nil(err)
+	assert.Equal(ks.Active().ID, reloaded.Active().ID)
+}
+
+func TestWriteSigningKeyLeavesOnlyKeyFile(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, err := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	assert.Nil(err)
+	path := filepath.Join(dir, ks.Active().ID+keyFileExt)
+
+	assert.Nil(writeSigningKey(path, ks.Active()))
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal([]string{ks.Active().ID + keyFileExt}, names, "no temporary file is left behind")
+	info, err := os.Stat(path)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+	key, err := readSigningKey(path)
+	assert.Nil(err)
+	assert.Equal(ks.Active().ID, key.ID)
+}
+
+func TestLoadKeySetUnsupportedAlgorithm(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := LoadKeySet(t.TempDir(), "HS256", 2, 0)
+
+	assert.ErrorIs(err, ErrUnsupportedAlg)
+}
+
+func TestKeySetSignAndParse(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmES256, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			assert := assert.New(t)
+			ks, _ := LoadKeySet(t.TempDir(), algorithm, 2, 0)
+
+			token, err := ks.Sign(jwt.MapClaims{"name": "John Doe"}, time.Minute)
+			claims, parseErr := ks.Parse(token)
+
+			assert.Nil(err)
+			assert.Nil(parseErr)
+			assert.Equal("John Doe", claims["name"])
+
+			parsed, _ := jwt.Parse(token, nil)
+			assert.Equal(algorithm, parsed.Method.Alg())
+			assert.Equal(ks.Active().ID, parsed.Header["kid"])
+		})
+	}
+}
+
+func TestKeySetParseExpired(t *testing.T) {
+	assert := assert.New(t)
+	ks, _ := LoadKeySet(t.TempDir(), AlgorithmES256, 2, 0)
+
+	token, _ := ks.Sign(jwt.MapClaims{}, -time.Second)
+	_, err := ks.Parse(token)
+
+	assert.NotNil(err)
+}
+
+func TestKeySetRotate(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	first := ks.Active()
+	oldToken, _ := ks.Sign(jwt.MapClaims{}, time.Minute)
+
+	second, err := ks.Rotate()
+	newToken, _ := ks.Sign(jwt.MapClaims{}, time.Minute)
+
+	assert.Nil(err)
+	assert.NotEqual(first.ID, second.ID)
+	assert.Equal(second.ID, ks.Active().ID)
+	_, err = ks.Parse(oldToken)
+	assert.Nil(err, "tokens of the previous key stay valid")
+	_, err = ks.Parse(newToken)
+	assert.Nil(err)
+
+	_, err = ks.Rotate()
+	assert.Nil(err)
+	_, err = ks.Parse(oldToken)
+	assert.ErrorIs(err, ErrUnknownKey)
+	assert.NoFileExists(filepath.Join(dir, first.ID+keyFileExt))
+	_, err = ks.Parse(newToken)
+	assert.Nil(err)
+}
+
+func TestKeySetReload(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	other, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+
+	rotated, _ := other.Rotate()
+	token, _ := other.Sign(jwt.MapClaims{}, time.Minute)
+
+	_, err := ks.Parse(token)
+	assert.ErrorIs(err, ErrUnknownKey)
+
+	assert.Nil(ks.Reload())
+	assert.Equal(rotated.ID, ks.Active().ID)
+	_, err = ks.Parse(token)
+	assert.Nil(err)
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	assert := assert.New(t)
+	ks, _ := LoadKeySet(t.TempDir(), AlgorithmES256, 2, 0)
+	rsaKeys, _ := LoadKeySet(t.TempDir(), AlgorithmRS256, 2, 0)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{})
+	forged.Header["kid"] = ks.Active().ID
+	token, _ := forged.SignedString(rsaKeys.Active().Private)
+	_, err := ks.Parse(token)
+
+	assert.NotNil(err)
+}
+
+func TestKeySetLegacyToken(t *testing.T) {
+	assert := assert.New(t)
+	ks, _ := LoadKeySet(t.TempDir(), AlgorithmES256, 2, 0)
+	t.Setenv(jwtEnvKey, "legacy-secret")
+
+	token, _ := NewJwtToken(jwt.MapClaims{"name": "John Doe"}, nil)
+	claims, err := ks.Parse(token)
+
+	assert.Nil(err)
+	assert.Equal("John Doe", claims["name"])
+
+	t.Setenv(jwtEnvKey, "")
+	_, err = ks.Parse(token)
+	assert.NotNil(err)
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	assert := assert.New(t)
+	ks, _ := LoadKeySet(t.TempDir(), AlgorithmES256, 2, 0)
+	_, _ = ks.Rotate()
+
+	jwks := ks.JWKS()
+
+	assert.Len(jwks.Keys, 2)
+	key := jwks.Keys[0]
+	assert.Equal(ks.Active().ID, key.Kid)
+	assert.Equal("EC", key.Kty)
+	assert.Equal("P-256", key.Crv)
+	assert.Equal(AlgorithmES256, key.Alg)
+	assert.Equal("sig", key.Use)
+	assert.Len(key.X, 43)
+	assert.Len(key.Y, 43)
+
+	rsaKeys, _ := LoadKeySet(t.TempDir(), AlgorithmRS256, 2, 0)
+	rsaKey := rsaKeys.JWKS().Keys[0]
+	assert.Equal("RSA", rsaKey.Kty)
+	assert.Equal("AQAB", rsaKey.E)
+	assert.NotEmpty(rsaKey.N)
+}
+
+func TestKeySetCreationTimeIgnoresFileTime(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	created := ks.Active().CreatedAt
+	path := filepath.Join(dir, ks.Active().ID+keyFileExt)
+	old := time.Now().Add(-365 * 24 * time.Hour)
+
+	assert.Nil(os.Chtimes(path, old, old))
+	assert.Nil(ks.Reload())
+
+	assert.True(created.Equal(ks.Active().CreatedAt))
+}
+
+func TestKeySetCreationTimeFromKid(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	key, _ := generateSigningKey(AlgorithmES256)
+	der, _ := x509.MarshalPKCS8PrivateKey(key.Private)
+	path := filepath.Join(dir, key.ID+keyFileExt)
+	assert.Nil(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	ks, err := LoadKeySet(dir, AlgorithmES256, 2, 0)
+
+	assert.Nil(err)
+	assert.Equal(key.ID, ks.Active().ID)
+	assert.True(key.CreatedAt.Truncate(time.Second).Equal(ks.Active().CreatedAt))
+}
+
+func TestKeySetRotateIfOlder(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	other, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	first := ks.Active()
+
+	key, rotated, err := ks.RotateIfOlder(time.Hour)
+	assert.Nil(err)
+	assert.False(rotated)
+	assert.Equal(first.ID, key.ID)
+
+	key, rotated, err = ks.RotateIfOlder(0)
+	assert.Nil(err)
+	assert.True(rotated)
+
+	_, rotated, err = other.RotateIfOlder(time.Hour)
+	assert.Nil(err)
+	assert.False(rotated, "a key rotated by another instance is picked up instead")
+	assert.Equal(key.ID, other.Active().ID)
+}
+
+func TestKeySetRotationLock(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, _ := LoadKeySet(dir, AlgorithmES256, 2, 0)
+	lock := filepath.Join(dir, lockFileName)
+	assert.Nil(os.WriteFile(lock, nil, 0o600))
+
+	_, err := ks.Rotate()
+	assert.ErrorIs(err, ErrRotationLocked)
+	_, _, err = ks.RotateIfOlder(0)
+	assert.ErrorIs(err, ErrRotationLocked)
+
+	stale := time.Now().Add(-2 * lockStaleAfter)
+	assert.Nil(os.Chtimes(lock, stale, stale))
+	_, err = ks.Rotate()
+	assert.Nil(err, "a stale lock is broken")
+	assert.NoFileExists(lock)
+}
+
+func TestKeySetKeepsYoungKeys(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	ks, _ := LoadKeySet(dir, AlgorithmES256, 1, time.Hour)
+	first := ks.Active()
+	token, _ := ks.Sign(jwt.MapClaims{}, time.Minute)
+
+	_, err := ks.Rotate()
+
+	assert.Nil(err)
+	assert.FileExists(filepath.Join(dir, first.ID+keyFileExt))
+	_, err = ks.Parse(token)
+	assert.Nil(err, "keys younger than keepFor are retained")
+}
@@ -1,50 +1,108 @@
 package auth
 
 import (
-	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha512"
-	"encoding/base32"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"io"
-	"os"
 	"strings"
+
+	"golang.org/x/crypto/argon2"
 )
 
-func EncryptPassword(password string) (string, string) {
+// Argon2id parameters for new hashes. Stored hashes carry their own
+// parameters, so raising these only affects passwords hashed afterwards;
+// NeedsRehash reports the older ones.
+const (
+	argon2Time    uint32 = 3
+	argon2Memory  uint32 = 64 * 1024
+	argon2Threads uint8  = 2
+	argon2KeyLen  uint32 = 32
+	argon2SaltLen        = 16
+)
 
-	passwordByt := []byte(password)
-	salt := generatePwdSalt(passwordByt)
+const argon2Prefix = "$argon2id$"
 
-	strSalt := strings.ToLower(base32.HexEncoding.EncodeToString(salt))
-	strHash := applyHash(strSalt, password)
+var ErrInvalidHash = errors.New("invalid password hash")
 
-	return strSalt, strHash
+// HashPassword hashes password with argon2id and returns it in the PHC string
+// format: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
+// VerifyPassword checks password against a stored hash. Argon2id hashes are
+// self-describing; anything else is treated as a legacy SHA-512 hex digest
+// of strSalt+password.
 func VerifyPassword(password string, strSalt string, strHash string) bool {
-	strNewHash := applyHash(strSalt, password)
-	return strNewHash == strHash
+	if !strings.HasPrefix(strHash, argon2Prefix) {
+		return subtle.ConstantTimeCompare([]byte(applyHash(strSalt, password)), []byte(strHash)) == 1
+	}
+
+	params, salt, key, err := decodeArgon2Hash(strHash)
+	if err != nil {
+		return false
+	}
+	newKey := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(newKey, key) == 1
+}
+
+// NeedsRehash reports whether strHash was produced by a legacy algorithm or
+// with weaker parameters than HashPassword currently uses.
+func NeedsRehash(strHash string) bool {
+	params, _, key, err := decodeArgon2Hash(strHash)
+	if err != nil {
+		return true
+	}
+	return params.time < argon2Time ||
+		params.memory < argon2Memory ||
+		params.threads < argon2Threads ||
+		uint32(len(key)) < argon2KeyLen
 }
 
 // private functions
-func generatePwdSalt(secret []byte) []byte {
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
 
-	const saltSize = 16
+func decodeArgon2Hash(strHash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(strHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
 
-	buf := make([]byte, saltSize, saltSize+md5.Size)
-	_, err := io.ReadFull(rand.Reader, buf)
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
 
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		fmt.Printf("random read failed: %v", err)
-		os.Exit(1)
+		return params, nil, nil, ErrInvalidHash
 	}
-
-	hash := md5.New()
-	hash.Write(buf)
-	hash.Write(secret)
-
-	return hash.Sum(buf)
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+	return params, salt, key, nil
 }
 
 func applyHash(salt, secret string) string {
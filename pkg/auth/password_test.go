@@ -2,30 +2,39 @@ package auth
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
-func TestEncryptPassword(t *testing.T) {
+// legacySalt and legacyHash follow the SHA-512 scheme used before argon2id,
+// for the password "pass".
+const legacySalt = "9pbd2ar3fheb0dttdlmdcv2p0e5ikucvp0ikqqhlpt2cfd3pfgkg"
+
+var legacyHash = applyHash(legacySalt, "pass")
+
+func TestHashPassword(t *testing.T) {
 	assert := assert.New(t)
 	const password = "pass"
 
-	saltSrt, hashStr := EncryptPassword(password)
+	hashStr, err := HashPassword(password)
+
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(hashStr, "$argon2id$v=19$m=65536,t=3,p=2$"))
+	assert.NotContains(hashStr, password)
 
-	assert.NotEmpty(saltSrt)
-	assert.NotEmpty(hashStr)
-	assert.NotEqual(password, hashStr)
+	other, err := HashPassword(password)
+	assert.Nil(err)
+	assert.NotEqual(hashStr, other)
 }
 
 func TestVerifyPassword(t *testing.T) {
 	assert := assert.New(t)
 	const password = "pass"
 
-	saltSrt, hashStr := EncryptPassword(password)
-	ok := VerifyPassword(password, saltSrt, hashStr)
+	hashStr, err := HashPassword(password)
+	ok := VerifyPassword(password, "", hashStr)
 
-	assert.NotEmpty(saltSrt)
-	assert.NotEmpty(hashStr)
-	assert.NotEqual(password, hashStr)
+	assert.Nil(err)
 	assert.True(ok)
 }
 
@@ -33,11 +42,29 @@ func TestVerifyPasswordFail(t *testing.T) {
 	assert := assert.New(t)
 	const password = "pass"
 
-	saltSrt, hashStr := EncryptPassword(password)
-	ok := VerifyPassword(password, saltSrt, hashStr+"fail")
+	hashStr, err := HashPassword(password)
+
+	assert.Nil(err)
+	assert.False(VerifyPassword("wrong", "", hashStr))
+	assert.False(VerifyPassword(password, "", hashStr+"fail"))
+	assert.False(VerifyPassword(password, "", "$argon2id$v=19$m=65536$broken"))
+}
+
+func TestVerifyLegacyPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(VerifyPassword("pass", legacySalt, legacyHash))
+	assert.False(VerifyPassword("wrong", legacySalt, legacyHash))
+	assert.False(VerifyPassword("pass", legacySalt, legacyHash+"fail"))
+}
+
+func TestNeedsRehash(t *testing.T) {
+	assert := assert.New(t)
+
+	hashStr, err := HashPassword("pass")
 
-	assert.NotEmpty(saltSrt)
-	assert.NotEmpty(hashStr)
-	assert.NotEqual(password, hashStr)
-	assert.False(ok)
+	assert.Nil(err)
+	assert.False(NeedsRehash(hashStr))
+	assert.True(NeedsRehash(legacyHash))
+	assert.True(NeedsRehash("$argon2id$v=19$m=4096,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2hoYXNoaGFzaA"))
 }
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const refreshTokenBytes = 32
+
+// JwtClaimTokenID is the claim holding the unique ID of an access token, used
+// to deny it before it expires.
+const JwtClaimTokenID = "jti"
+
+// JwtClaimPurpose marks tokens that are not access tokens, such as login
+// challenges, and must not authenticate API calls.
+const JwtClaimPurpose = "purpose"
+
+// NewRefreshToken returns a random opaque refresh token. Only its hash should
+// be stored.
+func NewRefreshToken() (string, error) {
+	return newOpaqueToken("rt_")
+}
+
+// HashRefreshToken returns the hex SHA-256 of token.
+func HashRefreshToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+// NewEmailToken returns a random single-use token to send in an email link,
+// such as a password reset. Only its hash should be stored.
+func NewEmailToken() (string, error) {
+	return newOpaqueToken("et_")
+}
+
+// HashEmailToken returns the hex SHA-256 of token.
+func HashEmailToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+// private functions
+func newOpaqueToken(prefix string) (string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	assert := assert.New(t)
+
+	token, err := NewRefreshToken()
+	other, otherErr := NewRefreshToken()
+
+	assert.Nil(err)
+	assert.Nil(otherErr)
+	assert.True(strings.HasPrefix(token, "rt_"))
+	assert.Len(token, 3+2*refreshTokenBytes)
+	assert.NotEqual(token, other)
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	assert := assert.New(t)
+
+	token, _ := NewRefreshToken()
+
+	assert.Equal(HashRefreshToken(token), HashRefreshToken(token))
+	assert.NotEqual(token, HashRefreshToken(token))
+	assert.Len(HashRefreshToken(token), 64)
+}
+
+func TestNewEmailToken(t *testing.T) {
+	assert := assert.New(t)
+
+	token, err := NewEmailToken()
+
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(token, "et_"))
+	assert.Len(token, 3+2*refreshTokenBytes)
+	assert.Equal(HashEmailToken(token), HashRefreshToken(token))
+}
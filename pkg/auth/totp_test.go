@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the ASCII key "12345678901234567890" of the RFC 4226 and
+// RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTPVectors(t *testing.T) {
+	assert := assert.New(t)
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, code := range expected {
+		assert.Equal(code, hotp([]byte("12345678901234567890"), int64(counter), 6))
+	}
+}
+
+func TestTOTPCodeVectors(t *testing.T) {
+	assert := assert.New(t)
+	// RFC 6238 SHA-1 vectors, truncated to 6 digits.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, code := range vectors {
+		got, err := TOTPCode(rfcSecret, time.Unix(unix, 0))
+		assert.Nil(err)
+		assert.Equal(code, got, "time %d", unix)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1111111109, 0)
+
+	step, ok := VerifyTOTP(rfcSecret, "081804", now, 1, 0)
+	assert.True(ok)
+	assert.Equal(int64(1111111109/30), step)
+
+	_, ok = VerifyTOTP(rfcSecret, "081804", now.Add(30*time.Second), 1, 0)
+	assert.True(ok, "previous step is accepted within skew")
+
+	_, ok = VerifyTOTP(rfcSecret, "081804", now.Add(90*time.Second), 1, 0)
+	assert.False(ok, "steps outside skew are refused")
+
+	_, ok = VerifyTOTP(rfcSecret, "081804", now, 1, step)
+	assert.False(ok, "used steps cannot be replayed")
+
+	_, ok = VerifyTOTP(rfcSecret, "000000", now, 1, 0)
+	assert.False(ok)
+
+	_, ok = VerifyTOTP("not base32!", "081804", now, 1, 0)
+	assert.False(ok)
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	assert := assert.New(t)
+
+	secret, err := NewTOTPSecret()
+	code, codeErr := TOTPCode(secret, time.Now())
+
+	assert.Nil(err)
+	assert.Nil(codeErr)
+	assert.Len(secret, 32)
+	assert.Len(code, 6)
+}
+
+func TestTOTPURI(t *testing.T) {
+	assert := assert.New(t)
+
+	uri := TOTPURI("Payments API", "jane@example.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(strings.HasPrefix(uri, "otpauth://totp/Payments%20API:jane@example.com?"))
+	assert.Contains(uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(uri, "issuer=Payments+API")
+	assert.Contains(uri, "digits=6")
+	assert.Contains(uri, "period=30")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	codes, err := NewRecoveryCodes(10)
+
+	assert.Nil(err)
+	assert.Len(codes, 10)
+	assert.Regexp(`^[a-z2-7]{4}-[a-z2-7]{4}$`, codes[0])
+	assert.NotEqual(codes[0], codes[1])
+	assert.Equal(HashRecoveryCode(codes[0]), HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))))
+	assert.NotEqual(HashRecoveryCode(codes[0]), HashRecoveryCode(codes[1]))
+}
@@ -0,0 +1,157 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrShutdownTimeout is returned by Wait when workers or hooks were still
+// running when the shutdown timeout expired.
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager runs the long-lived parts of the process and shuts them down in
+// order on SIGINT, SIGTERM, a call to Shutdown or the first worker failure:
+//
+//  1. the context given to workers is cancelled,
+//  2. OnStop hooks run in registration order, e.g. to drain an HTTP server,
+//  3. workers are waited for,
+//  4. OnClose hooks run in registration order, e.g. to flush producers and
+//     close the database.
+//
+// All of it shares a single timeout.
+type Manager struct {
+	timeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   context.CancelFunc
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+	onStop  []hook
+	onClose []hook
+}
+
+// New returns a manager listening for SIGINT and SIGTERM.
+func New(timeout time.Duration) *Manager {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(ctx)
+	return &Manager{timeout: timeout, ctx: ctx, cancel: cancel, stop: stop}
+}
+
+// Context is cancelled when shutdown starts.
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Go runs a worker until it returns. Workers must return once ctx is done;
+// a worker returning an error before that triggers shutdown.
+func (m *Manager) Go(name string, run func(ctx context.Context) error) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := run(m.ctx); err != nil && m.ctx.Err() == nil {
+			m.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+}
+
+// OnStop registers fn to run as soon as shutdown starts, before workers are
+// waited for.
+func (m *Manager) OnStop(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onStop = append(m.onStop, hook{name: name, fn: fn})
+}
+
+// OnClose registers fn to run once every worker has returned.
+func (m *Manager) OnClose(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onClose = append(m.onClose, hook{name: name, fn: fn})
+}
+
+// Shutdown starts shutting down, as a signal would.
+func (m *Manager) Shutdown() {
+	m.cancel()
+}
+
+// Wait blocks until shutdown starts, then performs it and returns the first
+// worker failure joined with any errors of the shutdown itself.
+func (m *Manager) Wait() error {
+	<-m.ctx.Done()
+	m.stop()
+	log.Printf("Shutting down, waiting up to %s", m.timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	onStop, onClose := m.onStop, m.onClose
+	m.mu.Unlock()
+
+	errs := []error{m.failure()}
+	errs = append(errs, runHooks(ctx, onStop)...)
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("%w waiting for workers", ErrShutdownTimeout))
+	}
+
+	errs = append(errs, runHooks(ctx, onClose)...)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Printf("Shutdown complete")
+	return nil
+}
+
+func (m *Manager) fail(err error) {
+	m.mu.Lock()
+	if m.err == nil {
+		m.err = err
+	}
+	m.mu.Unlock()
+	log.Printf("Worker failed, shutting down: %v", err)
+	m.cancel()
+}
+
+func (m *Manager) failure() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// runHooks runs hooks in order, skipping the rest once ctx is done.
+func runHooks(ctx context.Context, hooks []hook) []error {
+	var errs []error
+	for _, h := range hooks {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("%w before %s", ErrShutdownTimeout, h.name))
+			continue
+		}
+		if err := h.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,129 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestManagerShutdownOrder(t *testing.T) {
+	assert := assert.New(t)
+	m := New(time.Second)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, step)
+	}
+
+	m.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		record("worker")
+		return nil
+	})
+	m.OnClose("db", func(ctx context.Context) error {
+		record("db")
+		return nil
+	})
+	m.OnStop("http", func(ctx context.Context) error {
+		record("http")
+		return nil
+	})
+	m.OnClose("producer", func(ctx context.Context) error {
+		record("producer")
+		return nil
+	})
+
+	m.Shutdown()
+	err := m.Wait()
+
+	assert.Nil(err)
+	assert.Equal([]string{"http", "worker", "db", "producer"}, order)
+}
+
+func TestManagerSignal(t *testing.T) {
+	assert := assert.New(t)
+	m := New(time.Second)
+	stopped := make(chan struct{})
+	m.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+
+	assert.Nil(syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	assert.Nil(m.Wait())
+	<-stopped
+}
+
+func TestManagerWorkerFailure(t *testing.T) {
+	assert := assert.New(t)
+	m := New(time.Second)
+	closed := false
+	m.OnClose("db", func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+
+	m.Go("consumer", func(ctx context.Context) error {
+		return errors.New("broker unreachable")
+	})
+	m.Go("server", func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("closed")
+	})
+	err := m.Wait()
+
+	assert.NotNil(err)
+	assert.Equal("consumer: broker unreachable", err.Error())
+	assert.True(closed)
+}
+
+func TestManagerTimeout(t *testing.T) {
+	assert := assert.New(t)
+	m := New(20 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+
+	m.Go("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	m.OnClose("db", func(ctx context.Context) error {
+		return nil
+	})
+
+	m.Shutdown()
+	err := m.Wait()
+
+	assert.ErrorIs(err, ErrShutdownTimeout)
+	assert.Contains(err.Error(), "waiting for workers")
+	assert.Contains(err.Error(), "before db")
+}
+
+func TestManagerHookError(t *testing.T) {
+	assert := assert.New(t)
+	m := New(time.Second)
+	m.OnStop("http", func(ctx context.Context) error {
+		return errors.New("drain failed")
+	})
+	flushed := false
+	m.OnClose("producer", func(ctx context.Context) error {
+		flushed = true
+		return nil
+	})
+
+	m.Shutdown()
+	err := m.Wait()
+
+	assert.EqualError(err, "http: drain failed")
+	assert.True(flushed, "later hooks still run")
+}
@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+	date := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	raw := string(Format("no-reply@example.com", Message{
+		To:      "jane@example.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	}, date))
+
+	assert.Contains(raw, "From: no-reply@example.com\r\n")
+	assert.Contains(raw, "To: jane@example.com\r\n")
+	assert.Contains(raw, "Subject: Reset your password\r\n")
+	assert.Contains(raw, "Date: Mon, 01 Jul 2024 12:00:00 +0000\r\n")
+	assert.True(strings.HasSuffix(raw, "\r\n\r\nline one\r\nline two"))
+}
+
+func TestFormatEncodesSubject(t *testing.T) {
+	assert := assert.New(t)
+
+	raw := string(Format("no-reply@example.com", Message{To: "jane@example.com", Subject: "Contraseña"}, time.Now()))
+
+	assert.Contains(raw, "Subject: =?utf-8?q?Contrase=C3=B1a?=\r\n")
+}
+
+func TestFileMailer(t *testing.T) {
+	assert := assert.New(t)
+	dir := filepath.Join(t.TempDir(), "mail")
+
+	mailer, err := NewFileMailer(dir, "no-reply@example.com")
+	assert.Nil(err)
+	assert.Nil(mailer.Send(Message{To: "jane@example.com", Subject: "Hi", Body: "Hello"}))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Len(files, 1)
+	assert.Contains(files[0], "jane_example.com")
+	content, _ := os.ReadFile(files[0])
+	assert.Contains(string(content), "Subject: Hi")
+}
+
+func TestMemoryMailer(t *testing.T) {
+	assert := assert.New(t)
+	mailer := NewMemoryMailer()
+
+	_ = mailer.Send(Message{To: "a@example.com"})
+	_ = mailer.Send(Message{To: "b@example.com"})
+	messages := mailer.Messages()
+	messages[0].To = "changed"
+
+	assert.Len(mailer.Messages(), 2)
+	assert.Equal("a@example.com", mailer.Messages()[0].To)
+	assert.Equal("b@example.com", mailer.Messages()[1].To)
+}
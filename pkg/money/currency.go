@@ -0,0 +1,70 @@
+package money
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// exponents holds the number of minor-unit digits of each supported ISO-4217 currency.
+var exponents = map[string]int{
+	"ARS": 2,
+	"AUD": 2,
+	"BHD": 3,
+	"BOB": 2,
+	"BRL": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CLF": 4,
+	"CLP": 0,
+	"CNY": 2,
+	"COP": 2,
+	"CRC": 2,
+	"DOP": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"GTQ": 2,
+	"HNL": 2,
+	"IQD": 3,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"LYD": 3,
+	"MXN": 2,
+	"NIO": 2,
+	"OMR": 3,
+	"PAB": 2,
+	"PEN": 2,
+	"PYG": 0,
+	"TND": 3,
+	"USD": 2,
+	"UYU": 2,
+	"VES": 2,
+	"VND": 0,
+}
+
+// Exponent returns the number of minor-unit digits of currency.
+func Exponent(currency string) (int, error) {
+	exponent, ok := exponents[strings.ToUpper(currency)]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	return exponent, nil
+}
+
+// Exponents returns the minor-unit digits of every supported currency.
+func Exponents() map[string]int {
+	copied := make(map[string]int, len(exponents))
+	for currency, exponent := range exponents {
+		copied[currency] = exponent
+	}
+	return copied
+}
+
+func IsSupported(currency string) bool {
+	_, err := Exponent(currency)
+	return err == nil
+}
@@ -0,0 +1,38 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Decimal is an amount as written by the client, without a currency. It
+// accepts both JSON strings ("10.50") and numbers (10.50) and keeps their
+// text untouched so it can be parsed exactly once the currency is known.
+type Decimal string
+
+func (d Decimal) Money(currency string) (Money, error) {
+	return Parse(string(d), currency)
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(d))
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*d = Decimal(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return ErrInvalidAmount
+	}
+	*d = Decimal(n.String())
+	return nil
+}
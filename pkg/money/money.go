@@ -0,0 +1,177 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrPrecision        = errors.New("amount has more decimals than the currency allows")
+	ErrOverflow         = errors.New("amount is too large")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+var decimalRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an exact amount held as an integer count of the currency's minor
+// units, e.g. {1050, "USD"} is 10.50 USD and {1050, "CLP"} is 1050 CLP.
+type Money struct {
+	Minor    int64  `json:"-"`
+	Currency string `json:"-"`
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: strings.ToUpper(currency)}
+}
+
+// Parse converts a decimal string such as "10.50" into Money. It fails rather
+// than round when amount has more decimals than the currency's exponent.
+func Parse(amount, currency string) (Money, error) {
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	amount = strings.TrimSpace(amount)
+	if !decimalRegex.MatchString(amount) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+	whole, fraction, _ := strings.Cut(amount, ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return Money{}, ErrPrecision
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrOverflow
+	}
+	if negative {
+		minor = -minor
+	}
+	return New(minor, currency), nil
+}
+
+// FromFloat converts a legacy floating point amount, rounding to the
+// currency's exponent. New code should use Parse.
+func FromFloat(amount float64, currency string) (Money, error) {
+	exponent, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return Money{}, ErrInvalidAmount
+	}
+	return Parse(strconv.FormatFloat(amount, 'f', exponent, 64), currency)
+}
+
+// String formats m as a decimal with exactly the currency's exponent digits.
+func (m Money) String() string {
+	exponent, err := Exponent(m.Currency)
+	if err != nil || exponent == 0 {
+		return strconv.FormatInt(m.Minor, 10)
+	}
+
+	digits := strconv.FormatInt(m.Minor, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == o.Currency
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if !m.SameCurrency(o) {
+		return m, ErrCurrencyMismatch
+	}
+	sum := m.Minor + o.Minor
+	if (sum > m.Minor) != (o.Minor > 0) {
+		return m, ErrOverflow
+	}
+	return New(sum, m.Currency), nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(New(-o.Minor, o.Currency))
+}
+
+// BasisPoints returns bps ten-thousandths of m, rounded half away from zero
+// to the currency's minor unit.
+func (m Money) BasisPoints(bps int64) Money {
+	product := m.Minor * bps
+	half := int64(5000)
+	if product < 0 {
+		half = -half
+	}
+	return New((product+half)/10000, m.Currency)
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if !m.SameCurrency(o) {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Minor < o.Minor:
+		return -1, nil
+	case m.Minor > o.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+type jsonMoney struct {
+	Value    Decimal `json:"value"`
+	Currency string  `json:"currency"`
+}
+
+// Number returns m as a JSON number in major units, e.g. 10.50, without
+// going through float64.
+func (m Money) Number() json.Number {
+	return json.Number(m.String())
+}
+
+// MarshalJSON encodes m as {"value":"10.50","currency":"USD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Value: Decimal(m.String()), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the value as a decimal string or a JSON number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var j jsonMoney
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	parsed, err := Parse(string(j.Value), j.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
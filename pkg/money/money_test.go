@@ -0,0 +1,168 @@
+package money
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := Parse("10.5", "usd")
+
+	assert.Nil(err)
+	assert.Equal(int64(1050), m.Minor)
+	assert.Equal("USD", m.Currency)
+	assert.Equal("10.50", m.String())
+}
+
+func TestParseExponent(t *testing.T) {
+	assert := assert.New(t)
+
+	clp, clpErr := Parse("1050", "CLP")
+	kwd, kwdErr := Parse("1.005", "KWD")
+
+	assert.Nil(clpErr)
+	assert.Nil(kwdErr)
+	assert.Equal(int64(1050), clp.Minor)
+	assert.Equal("1050", clp.String())
+	assert.Equal(int64(1005), kwd.Minor)
+	assert.Equal("1.005", kwd.String())
+}
+
+func TestExponents(t *testing.T) {
+	assert := assert.New(t)
+
+	exponents := Exponents()
+	exponents["USD"] = 5
+
+	assert.Equal(0, Exponents()["CLP"])
+	assert.Equal(2, Exponents()["USD"], "the returned map is a copy")
+	assert.Len(Exponents(), len(exponents))
+}
+
+func TestParseErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, precisionErr := Parse("10.505", "USD")
+	_, clpErr := Parse("10.5", "CLP")
+	_, amountErr := Parse("10,50", "USD")
+	_, currencyErr := Parse("10.50", "XXX")
+	_, overflowErr := Parse("99999999999999999999", "USD")
+
+	assert.Equal(ErrPrecision, precisionErr)
+	assert.Equal(ErrPrecision, clpErr)
+	assert.Equal(ErrInvalidAmount, amountErr)
+	assert.Equal(ErrUnknownCurrency, currencyErr)
+	assert.Equal(ErrOverflow, overflowErr)
+}
+
+func TestParseTrailingZeros(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := Parse("10.5000", "USD")
+
+	assert.Nil(err)
+	assert.Equal(int64(1050), m.Minor)
+}
+
+func TestFromFloat(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := FromFloat(0.1+0.2, "USD")
+
+	assert.Nil(err)
+	assert.Equal(int64(30), m.Minor)
+}
+
+func TestString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("0.05", New(5, "USD").String())
+	assert.Equal("-0.05", New(-5, "USD").String())
+	assert.Equal("0.000", New(0, "KWD").String())
+	assert.Equal("123", New(123, "JPY").String())
+}
+
+func TestNumber(t *testing.T) {
+	assert := assert.New(t)
+
+	body, err := json.Marshal(map[string]interface{}{"amount": New(1050, "USD").Number()})
+
+	assert.Nil(err)
+	assert.Equal(`{"amount":10.50}`, string(body))
+	assert.Equal(json.Number("1050"), New(1050, "CLP").Number())
+}
+
+func TestAddSub(t *testing.T) {
+	assert := assert.New(t)
+
+	sum, sumErr := New(1050, "USD").Add(New(25, "USD"))
+	diff, diffErr := New(1050, "USD").Sub(New(50, "USD"))
+	_, mismatchErr := New(1050, "USD").Add(New(25, "EUR"))
+
+	assert.Nil(sumErr)
+	assert.Nil(diffErr)
+	assert.Equal(New(1075, "USD"), sum)
+	assert.Equal(New(1000, "USD"), diff)
+	assert.Equal(ErrCurrencyMismatch, mismatchErr)
+}
+
+func TestCmp(t *testing.T) {
+	assert := assert.New(t)
+
+	less, _ := New(1, "USD").Cmp(New(2, "USD"))
+	equal, _ := New(2, "USD").Cmp(New(2, "USD"))
+	greater, _ := New(3, "USD").Cmp(New(2, "USD"))
+	_, err := New(3, "USD").Cmp(New(2, "CLP"))
+
+	assert.Equal(-1, less)
+	assert.Equal(0, equal)
+	assert.Equal(1, greater)
+	assert.Equal(ErrCurrencyMismatch, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := json.Marshal(New(1050, "USD"))
+
+	assert.Nil(err)
+	assert.JSONEq(`{"value":"10.50","currency":"USD"}`, string(b))
+
+	var fromString, fromNumber Money
+	stringErr := json.Unmarshal([]byte(`{"value":"10.50","currency":"USD"}`), &fromString)
+	numberErr := json.Unmarshal([]byte(`{"value":10.5,"currency":"USD"}`), &fromNumber)
+
+	assert.Nil(stringErr)
+	assert.Nil(numberErr)
+	assert.Equal(New(1050, "USD"), fromString)
+	assert.Equal(New(1050, "USD"), fromNumber)
+}
+
+func TestDecimalJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var body struct {
+		Amount   Decimal `json:"amount"`
+		Currency string  `json:"currency"`
+	}
+
+	err := json.Unmarshal([]byte(`{"amount":0.1,"currency":"USD"}`), &body)
+	m, moneyErr := body.Amount.Money(body.Currency)
+
+	assert.Nil(err)
+	assert.Nil(moneyErr)
+	assert.Equal(Decimal("0.1"), body.Amount)
+	assert.Equal(int64(10), m.Minor)
+}
+
+func TestBasisPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(New(29, "USD"), New(1000, "USD").BasisPoints(290))
+	assert.Equal(New(3, "USD"), New(105, "USD").BasisPoints(250))
+	assert.Equal(New(-3, "USD"), New(-105, "USD").BasisPoints(250))
+	assert.Equal(New(0, "CLP"), New(1000, "CLP").BasisPoints(0))
+}
@@ -6,6 +6,22 @@ import (
 
 type Response struct {
 	Data interface{} `json:"data"`
+	Meta interface{} `json:"meta,omitempty"`
+}
+
+// ListMeta describes the page returned in Data by list endpoints.
+type ListMeta struct {
+	Total int64 `json:"total"`
+	Skip  int   `json:"skip"`
+	Limit int   `json:"limit"`
+}
+
+// CursorMeta describes a page returned with cursor paging. Next and Prev are
+// omitted when there is no page in that direction.
+type CursorMeta struct {
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Limit int    `json:"limit"`
 }
 
 func (r Response) reply(c *gin.Context, code int) {
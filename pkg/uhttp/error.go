@@ -3,9 +3,14 @@ package uhttp
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
+	"payment-payments-api/internal/models"
+	"payment-payments-api/internal/models/enums"
 	"payment-payments-api/internal/services"
+	"payment-payments-api/pkg/umdw"
 	"payment-payments-api/pkg/util"
+	"strconv"
 )
 
 func CustomError(c *gin.Context, code int, data interface{}) {
@@ -18,11 +23,81 @@ func CustomError(c *gin.Context, code int, data interface{}) {
 func Error(c *gin.Context, err error) {
 	var status int
 	var customErr *util.RequiredFieldError
+	var jwtErr *util.JWTError
+	var transitionErr *enums.TransitionError
+	var throttledErr *services.LoginThrottledError
 	switch {
 	case errors.As(err, &customErr):
 		status = http.StatusBadRequest
+	case errors.As(err, &jwtErr):
+		status = http.StatusUnauthorized
+	case errors.As(err, &throttledErr):
+		status = http.StatusTooManyRequests
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttledErr.RetryAfter.Seconds()))))
+	case errors.As(err, &transitionErr):
+		status = http.StatusConflict
 	case errors.Is(err, services.PaymentAlreadyRefunded):
 		status = http.StatusConflict
+	case errors.Is(err, services.IdempotencyKeyMismatch):
+		status = http.StatusConflict
+	case errors.Is(err, services.IdempotencyKeyInProgress):
+		status = http.StatusConflict
+	case errors.Is(err, services.RefundCurrencyMismatch):
+		status = http.StatusBadRequest
+	case errors.Is(err, umdw.ErrInvalidCursor):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.InvalidPaymentFilter):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.InvalidAmount):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.RefundExceedsBalance):
+		status = http.StatusConflict
+	case errors.Is(err, services.CaptureCurrencyMismatch):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.CaptureExceedsAuthorization):
+		status = http.StatusConflict
+	case errors.Is(err, services.AuthorizationExpired):
+		status = http.StatusConflict
+	case errors.Is(err, models.ErrImmutableLedger):
+		status = http.StatusConflict
+	case errors.Is(err, services.InvalidCard):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.CardNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.InvalidMerchant):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.MerchantCurrencyNotSupported):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.MerchantLimitExceeded):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, services.MerchantDisabled):
+		status = http.StatusConflict
+	case errors.Is(err, services.MerchantNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.InvalidApiKey):
+		status = http.StatusUnauthorized
+	case errors.Is(err, services.ApiKeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.InvalidUser):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.InvalidCredentials),
+		errors.Is(err, services.InvalidRefreshToken),
+		errors.Is(err, services.RefreshTokenReused),
+		errors.Is(err, services.InvalidChallenge),
+		errors.Is(err, services.InvalidTwoFactorCode):
+		status = http.StatusUnauthorized
+	case errors.Is(err, services.TwoFactorNotEnabled),
+		errors.Is(err, services.TwoFactorAlreadyEnabled),
+		errors.Is(err, services.TwoFactorRequired):
+		status = http.StatusConflict
+	case errors.Is(err, services.InvalidUserToken):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.EmailAlreadyVerified):
+		status = http.StatusConflict
+	case errors.Is(err, services.UserEmailTaken):
+		status = http.StatusConflict
+	case errors.Is(err, services.UserNotFound):
+		status = http.StatusNotFound
 	case errors.Is(err, services.PaymentNotFound):
 		status = http.StatusNotFound
 	default:
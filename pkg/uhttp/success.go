@@ -15,3 +15,11 @@ func CustomSuccess(c *gin.Context, code int, msg string, data interface{}) {
 func Success(c *gin.Context, msg string, data interface{}) {
 	CustomSuccess(c, http.StatusOK, msg, data)
 }
+
+func SuccessList(c *gin.Context, msg string, data interface{}, meta interface{}) {
+	var response = Response{
+		Data: data,
+		Meta: meta,
+	}
+	response.reply(c, http.StatusOK)
+}
@@ -0,0 +1,111 @@
+package umdw
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by (CreatedAt, ID). Desc keeps the
+// order the list was first requested in; Before selects the rows preceding
+// the position instead of the ones following it.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+	Desc      bool      `json:"d,omitempty"`
+	Before    bool      `json:"b,omitempty"`
+}
+
+// CursorSigner turns cursors into opaque tokens and back. Tokens are signed
+// so clients cannot forge positions.
+type CursorSigner struct {
+	key []byte
+}
+
+func NewCursorSigner(key []byte) *CursorSigner {
+	return &CursorSigner{key: key}
+}
+
+func (s *CursorSigner) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+func (s *CursorSigner) Decode(token string) (*Cursor, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, s.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (s *CursorSigner) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// KeysetPage holds the cursors of the pages around the one returned. A nil
+// cursor means there is no page in that direction.
+type KeysetPage struct {
+	Next *Cursor
+	Prev *Cursor
+}
+
+// NewKeysetPage computes the neighbouring cursors of a page read from current.
+// first and last are the keys of the page's first and last rows in reading
+// order, nil when the page is empty. hasMore reports whether rows exist past
+// the page in the direction it was read.
+func NewKeysetPage(current *Cursor, desc, hasMore bool, first, last *Cursor) KeysetPage {
+	var page KeysetPage
+	backward := current != nil && current.Before
+
+	if first == nil || last == nil {
+		if current != nil {
+			reverse := Cursor{CreatedAt: current.CreatedAt, ID: current.ID, Desc: desc, Before: !backward}
+			if backward {
+				page.Next = &reverse
+			} else {
+				page.Prev = &reverse
+			}
+		}
+		return page
+	}
+
+	next := Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: desc}
+	prev := Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Desc: desc, Before: true}
+	if backward {
+		page.Next = &next
+		if hasMore {
+			page.Prev = &prev
+		}
+	} else {
+		if hasMore {
+			page.Next = &next
+		}
+		if current != nil {
+			page.Prev = &prev
+		}
+	}
+	return page
+}
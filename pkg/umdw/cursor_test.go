@@ -0,0 +1,71 @@
+package umdw
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCursorSignerRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	signer := NewCursorSigner([]byte("secret"))
+	cursor := Cursor{
+		CreatedAt: time.Date(2024, 3, 1, 10, 30, 0, 123456000, time.UTC),
+		ID:        "3f1c3a4e-6c1d-4f5a-9a43-7b1e1f6a2b10",
+		Desc:      true,
+		Before:    true,
+	}
+
+	decoded, err := signer.Decode(signer.Encode(cursor))
+
+	assert.Nil(err)
+	assert.True(cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(cursor.ID, decoded.ID)
+	assert.True(decoded.Desc)
+	assert.True(decoded.Before)
+}
+
+func TestCursorSignerRejectsTampering(t *testing.T) {
+	assert := assert.New(t)
+
+	signer := NewCursorSigner([]byte("secret"))
+	token := signer.Encode(Cursor{CreatedAt: time.Now(), ID: "a"})
+	forged := NewCursorSigner([]byte("other")).Encode(Cursor{CreatedAt: time.Now(), ID: "a"})
+
+	_, err := signer.Decode(forged)
+	assert.ErrorIs(err, ErrInvalidCursor)
+
+	_, err = signer.Decode("x" + token)
+	assert.ErrorIs(err, ErrInvalidCursor)
+
+	_, err = signer.Decode("not-a-cursor")
+	assert.ErrorIs(err, ErrInvalidCursor)
+}
+
+func TestNewKeysetPage(t *testing.T) {
+	assert := assert.New(t)
+
+	first := &Cursor{ID: "first"}
+	last := &Cursor{ID: "last"}
+
+	page := NewKeysetPage(nil, true, true, first, last)
+	assert.Nil(page.Prev)
+	assert.Equal("last", page.Next.ID)
+	assert.True(page.Next.Desc)
+	assert.False(page.Next.Before)
+
+	page = NewKeysetPage(&Cursor{ID: "c"}, false, false, first, last)
+	assert.Nil(page.Next)
+	assert.Equal("first", page.Prev.ID)
+	assert.True(page.Prev.Before)
+
+	page = NewKeysetPage(&Cursor{ID: "c", Before: true}, false, false, first, last)
+	assert.Nil(page.Prev)
+	assert.Equal("last", page.Next.ID)
+
+	page = NewKeysetPage(&Cursor{ID: "c"}, false, false, nil, nil)
+	assert.Nil(page.Next)
+	assert.Equal("c", page.Prev.ID)
+	assert.True(page.Prev.Before)
+}
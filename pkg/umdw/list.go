@@ -13,6 +13,10 @@ const (
 	by       = "by"
 	sortAsc  = "asc"
 	sortDesc = "desc"
+
+	cursor       = "cursor"
+	paging       = "paging"
+	pagingCursor = "cursor"
 )
 
 type List struct {
@@ -20,6 +24,12 @@ type List struct {
 	Skip  int    `json:"skip"`
 	Sort  string `json:"sort"`
 	By    string `json:"by"`
+
+	// Keyset is set when the client asked for cursor pagination, either with
+	// paging=cursor for the first page or by sending a cursor. Cursor holds
+	// the raw token, empty on the first page.
+	Keyset bool   `json:"keyset"`
+	Cursor string `json:"cursor"`
 }
 
 func (l *List) Set(skip, limit, sort, column string) error {
@@ -67,5 +77,11 @@ func ListContext(c *gin.Context) (*List, error) {
 		return nil, err
 	}
 
+	list.Cursor = c.Query(cursor)
+	list.Keyset = list.Cursor != "" || c.Query(paging) == pagingCursor
+	if list.Keyset && skFound {
+		return nil, errors.New("skip cannot be combined with cursor paging")
+	}
+
 	return &list, nil
 }
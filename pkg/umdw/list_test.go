@@ -38,3 +38,29 @@ func TestListContext(t *testing.T) {
 	assert.Equal("desc", list.Sort)
 	assert.Equal("name", list.By)
 }
+
+func TestListContextCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/testing/list?limit=5&cursor=abc.def", nil)
+
+	c := gin.Context{
+		Request: req,
+	}
+
+	list, err := ListContext(&c)
+
+	assert.Nil(err)
+	assert.True(list.Keyset)
+	assert.Equal("abc.def", list.Cursor)
+	assert.Equal(5, list.Limit)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/testing/list?paging=cursor&skip=10", nil)
+	c = gin.Context{
+		Request: req,
+	}
+
+	_, err = ListContext(&c)
+
+	assert.NotNil(err)
+}
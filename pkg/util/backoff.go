@@ -0,0 +1,22 @@
+package util
+
+import "time"
+
+// Backoff returns the exponential delay before retry number attempt (starting
+// at 1), doubling base on every attempt and never exceeding max.
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max || delay <= 0 {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Second, Backoff(time.Second, time.Minute, 0))
+	assert.Equal(time.Second, Backoff(time.Second, time.Minute, 1))
+	assert.Equal(2*time.Second, Backoff(time.Second, time.Minute, 2))
+	assert.Equal(8*time.Second, Backoff(time.Second, time.Minute, 4))
+}
+
+func TestBackoffMax(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Minute, Backoff(time.Second, time.Minute, 7))
+	assert.Equal(time.Minute, Backoff(time.Second, time.Minute, 200))
+}
@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TokenPrefix = "tok_"
+
+// NormalizePAN removes the spaces and dashes clients use to group card digits.
+func NormalizePAN(pan string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(pan)
+}
+
+// ValidPAN reports whether pan has 12 to 19 digits and passes the Luhn check.
+func ValidPAN(pan string) bool {
+	if len(pan) < 12 || len(pan) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(pan) - 1; i >= 0; i-- {
+		d := int(pan[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ValidExpiry reports whether expiry is a MM/YY date whose month has not ended at now.
+func ValidExpiry(expiry string, now time.Time) bool {
+	if len(expiry) != 5 || expiry[2] != '/' {
+		return false
+	}
+	month, err := strconv.Atoi(expiry[:2])
+	if err != nil || month < 1 || month > 12 {
+		return false
+	}
+	year, err := strconv.Atoi(expiry[3:])
+	if err != nil {
+		return false
+	}
+	endOfMonth := time.Date(2000+year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	return now.Before(endOfMonth)
+}
+
+func ValidCVC(cvc string) bool {
+	if len(cvc) < 3 || len(cvc) > 4 {
+		return false
+	}
+	_, err := strconv.Atoi(cvc)
+	return err == nil
+}
+
+func Bin(pan string) string {
+	if len(pan) < 6 {
+		return ""
+	}
+	return pan[:6]
+}
+
+func Last4(pan string) string {
+	if len(pan) < 4 {
+		return ""
+	}
+	return pan[len(pan)-4:]
+}
+
+// Mask hides every digit of pan except the BIN and the last four.
+func Mask(pan string) string {
+	if len(pan) < 10 {
+		return strings.Repeat("*", len(pan))
+	}
+	return Bin(pan) + strings.Repeat("*", len(pan)-10) + Last4(pan)
+}
+
+// Fingerprint identifies pan across tokens without storing it, using a keyed
+// hash so fingerprints cannot be brute-forced from the BIN and last four.
+func Fingerprint(key []byte, pan string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(pan))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidPAN(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(ValidPAN("4111111111111111"))
+	assert.True(ValidPAN(NormalizePAN("4111 1111-1111 1111")))
+	assert.False(ValidPAN("4111111111111112"))
+	assert.False(ValidPAN("41111111111a1111"))
+	assert.False(ValidPAN("42"))
+}
+
+func TestValidExpiry(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	assert.True(ValidExpiry("03/26", now))
+	assert.True(ValidExpiry("12/30", now))
+	assert.False(ValidExpiry("02/26", now))
+	assert.False(ValidExpiry("13/30", now))
+	assert.False(ValidExpiry("0330", now))
+}
+
+func TestValidCVC(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(ValidCVC("123"))
+	assert.True(ValidCVC("1234"))
+	assert.False(ValidCVC("12"))
+	assert.False(ValidCVC("12a"))
+}
+
+func TestMask(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("411111", Bin("4111111111111111"))
+	assert.Equal("1111", Last4("4111111111111111"))
+	assert.Equal("411111******1111", Mask("4111111111111111"))
+}
+
+func TestFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	a := Fingerprint(testKey, "4111111111111111")
+	b := Fingerprint(testKey, "4111111111111111")
+	c := Fingerprint([]byte("other"), "4111111111111111")
+
+	assert.Equal(a, b)
+	assert.NotEqual(a, c)
+	assert.Len(a, 64)
+}
+
+func TestNewToken(t *testing.T) {
+	assert := assert.New(t)
+
+	a, err := NewToken()
+	b, _ := NewToken()
+
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(a, TokenPrefix))
+	assert.NotEqual(a, b)
+}
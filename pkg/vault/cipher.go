@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidKey        = errors.New("vault key must be 32 bytes encoded in base64")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Cipher seals data with AES-256-GCM. Sealed output is the random nonce
+// followed by the ciphertext and tag.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// NewCipherFromBase64 builds a Cipher from a standard base64 encoded key, as
+// found in configuration.
+func NewCipherFromBase64(encodedKey string) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return NewCipher(key)
+}
+
+// Seal encrypts plaintext. additionalData is authenticated but not encrypted
+// and must be passed again to Open.
+func (c *Cipher) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (c *Cipher) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestSealOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := NewCipher(testKey)
+	sealed, sealErr := c.Seal([]byte("4111111111111111"), []byte("tok_1"))
+	opened, openErr := c.Open(sealed, []byte("tok_1"))
+
+	assert.Nil(err)
+	assert.Nil(sealErr)
+	assert.Nil(openErr)
+	assert.NotContains(string(sealed), "4111111111111111")
+	assert.Equal("4111111111111111", string(opened))
+}
+
+func TestOpenWrongAdditionalData(t *testing.T) {
+	assert := assert.New(t)
+
+	c, _ := NewCipher(testKey)
+	sealed, _ := c.Seal([]byte("4111111111111111"), []byte("tok_1"))
+	_, err := c.Open(sealed, []byte("tok_2"))
+
+	assert.Equal(ErrInvalidCiphertext, err)
+}
+
+func TestOpenTampered(t *testing.T) {
+	assert := assert.New(t)
+
+	c, _ := NewCipher(testKey)
+	sealed, _ := c.Seal([]byte("4111111111111111"), nil)
+	sealed[len(sealed)-1] ^= 1
+	_, err := c.Open(sealed, nil)
+	_, shortErr := c.Open([]byte{1, 2}, nil)
+
+	assert.Equal(ErrInvalidCiphertext, err)
+	assert.Equal(ErrInvalidCiphertext, shortErr)
+}
+
+func TestNewCipherFromBase64(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewCipherFromBase64(base64.StdEncoding.EncodeToString(testKey))
+	_, shortErr := NewCipherFromBase64(base64.StdEncoding.EncodeToString(testKey[:16]))
+	_, encodingErr := NewCipherFromBase64("not base64!")
+
+	assert.Nil(err)
+	assert.Equal(ErrInvalidKey, shortErr)
+	assert.Equal(ErrInvalidKey, encodingErr)
+}